@@ -11,16 +11,48 @@ import (
 	"time"
 
 	"github.com/temo927/go-msg-dispatcher/internal/app"
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
 	"github.com/temo927/go-msg-dispatcher/internal/infra/cache"
 	"github.com/temo927/go-msg-dispatcher/internal/infra/config"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/events"
 	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/metrics"
 	"github.com/temo927/go-msg-dispatcher/internal/infra/repository"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/smtp"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/tracing"
 	"github.com/temo927/go-msg-dispatcher/internal/infra/webhook"
+	"github.com/temo927/go-msg-dispatcher/internal/phone"
 	httpapi "github.com/temo927/go-msg-dispatcher/internal/transport/http"
 )
 
 func main() {
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Logger.Error("invalid configuration", "err", err)
+		os.Exit(1)
+	}
+	if err := log.Configure(log.Config{Format: cfg.LogFormat, Redact: cfg.LogRedact}, cfg.LogLevel); err != nil {
+		log.Logger.Error("invalid logging configuration", "err", err)
+		os.Exit(1)
+	}
+	if !phone.SupportedRegion(cfg.DefaultPhoneRegion) {
+		log.Logger.Error("unsupported DEFAULT_PHONE_REGION", "region", cfg.DefaultPhoneRegion)
+		os.Exit(1)
+	}
+	if cfg.DedupMode != httpapi.DedupReject && cfg.DedupMode != httpapi.DedupCollapse {
+		log.Logger.Error("unsupported DEDUP_MODE", "mode", cfg.DedupMode)
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.TraceExporter,
+		ServiceName: cfg.TraceServiceName,
+		SampleRatio: cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		log.Logger.Error("invalid tracing configuration", "err", err)
+		os.Exit(1)
+	}
 
 	db, err := repository.Connect(cfg.DBDSN)
 	if err != nil {
@@ -32,25 +64,256 @@ func main() {
 
 	cacheAdapter := cache.New(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, cfg.RedisTTL)
 
-	provider := webhook.NewClient(webhook.Config{
+	var tokenStore webhook.TokenStore
+	if cfg.WebhookOAuth2SharedCache {
+		tokenStore = cacheAdapter
+	}
+
+	smsConfig := webhook.Config{
 		URL:          cfg.WebhookURL,
 		AuthHeader:   cfg.WebhookAuthHeader,
 		AuthValue:    cfg.WebhookAuthValue,
 		AcceptAny2xx: cfg.AcceptAny2xx,
 		Timeout:      5 * time.Second,
-	})
+
+		SigningSecrets:  cfg.WebhookSigningSecrets,
+		SignatureHeader: cfg.WebhookSignatureHeader,
+
+		Method:            cfg.WebhookMethod,
+		Headers:           cfg.WebhookHeaders,
+		BodyFormat:        cfg.WebhookBodyFormat,
+		BodyTemplate:      cfg.WebhookBodyTemplate,
+		ResponseIDPath:    cfg.WebhookResponseIDPath,
+		ResponseErrorPath: cfg.WebhookResponseErrorPath,
+
+		TLS: webhook.TLSConfig{
+			CertFile:   cfg.WebhookTLSCertFile,
+			KeyFile:    cfg.WebhookTLSKeyFile,
+			CAFile:     cfg.WebhookTLSCAFile,
+			MinVersion: cfg.WebhookTLSMinVersion,
+			ServerName: cfg.WebhookTLSServerName,
+		},
+
+		OAuth2: webhook.OAuth2Config{
+			TokenURL:     cfg.WebhookOAuth2TokenURL,
+			ClientID:     cfg.WebhookOAuth2ClientID,
+			ClientSecret: cfg.WebhookOAuth2ClientSecret,
+			Scopes:       cfg.WebhookOAuth2Scopes,
+		},
+		TokenStore: tokenStore,
+	}
+	smsProvider, err := webhook.NewClient(smsConfig)
+	if err != nil {
+		log.Logger.Error("invalid webhook configuration", "err", err)
+		os.Exit(1)
+	}
+
+	providers := app.NewProviderRegistry()
+	providers.Register(domain.ChannelSMS, smsProvider)
+
+	if cfg.SMTPAddr != "" {
+		emailProvider, err := smtp.NewClient(smtp.Config{
+			Addr:            cfg.SMTPAddr,
+			Username:        cfg.SMTPUsername,
+			Password:        cfg.SMTPPassword,
+			From:            cfg.SMTPFrom,
+			DisableStartTLS: cfg.SMTPDisableStartTLS,
+		})
+		if err != nil {
+			log.Logger.Error("invalid smtp configuration", "err", err)
+			os.Exit(1)
+		}
+		providers.Register(domain.ChannelEmail, emailProvider)
+	}
+
+	if cfg.PushWebhookURL != "" {
+		pushProvider, err := webhook.NewClient(webhook.Config{
+			URL:            cfg.PushWebhookURL,
+			AuthHeader:     cfg.PushWebhookAuthHeader,
+			AuthValue:      cfg.PushWebhookAuthValue,
+			AcceptAny2xx:   cfg.AcceptAny2xx,
+			BodyTemplate:   cfg.PushWebhookBodyTemplate,
+			ResponseIDPath: cfg.PushWebhookResponseIDPath,
+		})
+		if err != nil {
+			log.Logger.Error("invalid push webhook configuration", "err", err)
+			os.Exit(1)
+		}
+		providers.Register(domain.ChannelPush, pushProvider)
+	}
+	log.Logger.Info("providers registered", "channels", providers.Channels())
+
+	tenantsRepo := repository.NewTenantsRepo(db)
+	providers.UseTenantProviders(tenantsRepo, func(channel string, p domain.ProviderConfig) (domain.Provider, error) {
+		// Tenant providers share the request shape of the global sms webhook
+		// but never its credentials, TLS client certificate or signing keys.
+		c := webhook.Config{
+			URL:               p.URL,
+			AuthHeader:        p.AuthHeader,
+			AuthValue:         p.AuthValue,
+			AcceptAny2xx:      smsConfig.AcceptAny2xx,
+			Timeout:           smsConfig.Timeout,
+			Method:            smsConfig.Method,
+			Headers:           p.Headers,
+			BodyFormat:        smsConfig.BodyFormat,
+			BodyTemplate:      p.BodyTemplate,
+			ResponseIDPath:    p.ResponseIDPath,
+			ResponseErrorPath: smsConfig.ResponseErrorPath,
+		}
+		if channel == domain.ChannelSMS {
+			if c.BodyTemplate == "" {
+				c.BodyTemplate = smsConfig.BodyTemplate
+			}
+			if c.ResponseIDPath == "" {
+				c.ResponseIDPath = smsConfig.ResponseIDPath
+			}
+		}
+		return webhook.NewClient(c)
+	}, cfg.TenantCacheTTL)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	eventBus := events.NewBus(cacheAdapter, cfg.EventsChannel)
+	go eventBus.Run(ctx)
 
 	messageRepo := repository.NewMessagesRepo(db)
+	messageRepo.UseEvents(eventBus)
+
+	var outboxSinks app.OutboxSinks
+	switch cfg.OutboxSink {
+	case "none":
+	case "redis":
+		stream := cache.StreamConfig{Name: cfg.OutboxStream, MaxLen: cfg.OutboxStreamMaxLen, Group: cfg.OutboxStreamGroup}
+		if err := cacheAdapter.EnsureStream(ctx, stream); err != nil {
+			log.Logger.Warn("outbox stream group not created", "err", err)
+		}
+		outboxSinks = append(outboxSinks, cache.NewOutboxStream(cacheAdapter, stream))
+	case "webhook":
+		sink, err := webhook.NewOutboxWebhook(cfg.OutboxWebhookURL, webhook.StatusCallbackConfig{
+			SigningSecrets: cfg.OutboxWebhookSigningSecrets,
+		})
+		if err != nil {
+			log.Logger.Error("invalid outbox webhook configuration", "err", err)
+			os.Exit(1)
+		}
+		outboxSinks = append(outboxSinks, sink)
+	default:
+		log.Logger.Error("unsupported OUTBOX_SINK", "sink", cfg.OutboxSink)
+		os.Exit(1)
+	}
+	// The sent stream is fed from the outbox too, so a send committed
+	// while Redis is down still reaches it once Redis is back.
+	if cfg.SentStream != "" {
+		stream := cache.StreamConfig{Name: cfg.SentStream, MaxLen: cfg.SentStreamMaxLen, Group: cfg.SentStreamGroup}
+		// Consumers can create the group themselves, so Redis being down at
+		// startup isn't fatal.
+		if err := cacheAdapter.EnsureStream(ctx, stream); err != nil {
+			log.Logger.Warn("sent stream group not created", "err", err)
+		}
+		outboxSinks = append(outboxSinks, cache.NewSentStream(cacheAdapter, stream))
+	}
+	if len(outboxSinks) > 0 {
+		messageRepo.UseOutbox()
+		relay := app.NewOutboxRelay(repository.NewOutboxRepo(db), outboxSinks, app.OutboxRelayConfig{
+			Interval:   cfg.OutboxInterval,
+			BatchSize:  cfg.OutboxBatchSize,
+			Backoff:    cfg.OutboxBackoff,
+			MaxBackoff: cfg.OutboxMaxBackoff,
+			Retention:  cfg.OutboxRetention,
+		})
+		go relay.Run(ctx)
+		log.Logger.Info("outbox relay started", "sink", cfg.OutboxSink, "sent_stream", cfg.SentStream)
+	}
+	metrics.RegisterQueueDepth(messageRepo)
+	suppressions := cache.NewSuppressionCache(
+		repository.NewSuppressionsRepo(db),
+		cacheAdapter,
+		cfg.SuppressionCacheTTL,
+	)
 	sender := app.NewSender(
 		messageRepo,
-		provider,
+		providers,
 		cacheAdapter,
+		suppressions,
 		app.SenderConfig{MaxRetries: cfg.MaxRetries},
 	)
+	sender.UseEvents(eventBus)
+
+	statusCallbacksRepo := repository.NewStatusCallbacksRepo(db)
+	if cfg.StatusCallbacksEnabled {
+		poster, err := webhook.NewStatusCallbackClient(webhook.StatusCallbackConfig{
+			Timeout:         cfg.StatusCallbackTimeout,
+			SignatureHeader: cfg.StatusCallbackSignatureHeader,
+			AllowedHosts:    cfg.StatusCallbackAllowedHosts,
+		})
+		if err != nil {
+			log.Logger.Error("invalid status callback configuration", "err", err)
+			os.Exit(1)
+		}
+		messageRepo.UseStatusCallbacks()
+		statusCallbacks := app.NewStatusCallbacks(statusCallbacksRepo, tenantsRepo, poster, app.StatusCallbackConfig{
+			Interval:    cfg.StatusCallbackInterval,
+			BatchSize:   cfg.StatusCallbackBatchSize,
+			MaxAttempts: cfg.StatusCallbackMaxAttempts,
+			Backoff:     cfg.StatusCallbackBackoff,
+			MaxBackoff:  cfg.StatusCallbackMaxBackoff,
+			Lease:       2 * cfg.StatusCallbackTimeout,
+		})
+		go statusCallbacks.Run(ctx)
+	} else {
+		log.Logger.Info("STATUS_CALLBACKS_ENABLED is off; callback_url is disabled")
+	}
 	scheduler := app.NewScheduler(messageRepo, sender, cfg.TickInterval, cfg.BatchSize)
 
-	handlers := httpapi.NewHandlers(scheduler, messageRepo)
-	router := httpapi.NewRouter(handlers)
+	templatesRepo := repository.NewTemplatesRepo(db)
+	apiKeysRepo := repository.NewAPIKeysRepo(db)
+
+	if err := config.CheckBootstrapKey(cfg.AuthBootstrapKey); err != nil {
+		log.Logger.Error("invalid AUTH_BOOTSTRAP_KEY", "err", err)
+		os.Exit(1)
+	}
+	if cfg.AuthEnabled && cfg.AuthBootstrapKey == "" {
+		log.Logger.Warn("AUTH_BOOTSTRAP_KEY is empty; only existing API keys can authenticate")
+	}
+	if !cfg.AuthEnabled {
+		log.Logger.Warn("API key authentication is disabled")
+	}
+	auth := httpapi.NewAuthenticator(apiKeysRepo, httpapi.AuthConfig{
+		Enabled:      cfg.AuthEnabled,
+		BootstrapKey: cfg.AuthBootstrapKey,
+	})
+
+	handlers := httpapi.NewHandlers(scheduler, messageRepo, templatesRepo, suppressions, cacheAdapter, apiKeysRepo, tenantsRepo, statusCallbacksRepo, eventBus, httpapi.HandlersConfig{
+		DeliveryCallbackSecret:     cfg.DeliveryCallbackSecret,
+		Channels:                   providers.Channels(),
+		DefaultPhoneRegion:         cfg.DefaultPhoneRegion,
+		MaxMessageChars:            cfg.MaxMessageChars,
+		DedupWindow:                cfg.DedupWindow,
+		DedupMode:                  cfg.DedupMode,
+		StatusCallbacks:            cfg.StatusCallbacksEnabled,
+		StatusCallbackAllowedHosts: cfg.StatusCallbackAllowedHosts,
+	})
+
+	checks := []httpapi.HealthCheck{
+		{Name: "postgres", Check: db.PingContext},
+		{Name: "redis", Check: cacheAdapter.Ping},
+	}
+	if cfg.HealthCheckWebhook {
+		checks = append(checks, httpapi.HealthCheck{Name: "webhook", Check: smsProvider.Reachable})
+	}
+	health := httpapi.NewHealth(cfg.HealthCheckTimeout, checks...)
+
+	var validator *httpapi.RequestValidator
+	if cfg.ValidateRequests {
+		validator, err = httpapi.NewRequestValidator()
+		if err != nil {
+			log.Logger.Error("request validation unavailable", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	router := httpapi.NewRouter(handlers, auth, health, validator)
 
 	port := cfg.Port
 	if port == "" {
@@ -63,9 +326,8 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
-
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
+	// Shutdown waits for open requests, and event streams never end on their own.
+	srv.RegisterOnShutdown(eventBus.Close)
 
 	go func() {
 		log.Logger.Info("server started", "addr", srv.Addr)
@@ -82,6 +344,10 @@ func main() {
 	<-ctx.Done()
 	log.Logger.Info("shutdown initiated")
 
+	// Fail readiness first and give the load balancer time to notice.
+	health.Drain()
+	time.Sleep(cfg.ShutdownDrainDelay)
+
 	_ = scheduler.Stop()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -89,6 +355,9 @@ func main() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Logger.Error("graceful shutdown failed", "err", err)
 	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Logger.Error("flushing traces failed", "err", err)
+	}
 
 	log.Logger.Info("server stopped cleanly")
 }
@@ -0,0 +1,34 @@
+// Package apikey generates API keys and derives the hash they are stored and
+// looked up by.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+const (
+	keyPrefix = "mdk_"
+	// PrefixLen is how much of a key is kept in clear to tell keys apart.
+	PrefixLen = len(keyPrefix) + 8
+)
+
+// Generate returns a new random key. The plaintext is only ever shown once;
+// store Hash(key) and the prefix.
+func Generate() (key, prefix string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("generate api key: %w", err)
+	}
+	key = keyPrefix + hex.EncodeToString(b)
+	return key, key[:PrefixLen], nil
+}
+
+// Hash returns the hex SHA-256 of key. Keys are long random strings, so a
+// plain digest is enough; there is nothing to brute force.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
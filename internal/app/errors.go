@@ -1,6 +1,10 @@
 package app
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
 
 var (
 	ErrSendFailed     = errors.New("provider send failed")
@@ -8,4 +12,15 @@ var (
 	ErrMarkFailed     = errors.New("mark failed")
 	ErrAlreadyRunning = errors.New("scheduler already running")
 	ErrNotRunning     = errors.New("scheduler not running")
+
+	ErrUnsupportedChannel = errors.New("no provider registered for channel")
+	ErrSuppressed         = errors.New("recipient is suppressed")
 )
+
+// internalError marks a failure on our side rather than the provider's, so
+// it is reported as domain.SendErrorInternal.
+type internalError struct{ error }
+
+func (internalError) ErrorCode() string { return domain.SendErrorInternal }
+
+func (e internalError) Unwrap() error { return e.error }
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/metrics"
+)
+
+type OutboxRelayConfig struct {
+	Interval  time.Duration
+	BatchSize int
+	// Backoff is the delay before an event that failed is tried again; it
+	// doubles with every further attempt, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Lease is how long a claimed batch is hidden from other replicas; it
+	// must outlast publishing it.
+	Lease time.Duration
+	// Retention is how long published events are kept before being pruned.
+	Retention time.Duration
+}
+
+// OutboxSinks publishes each event to every sink in turn. An event one of
+// them refuses is retried on all of them, so sinks see it at least once.
+type OutboxSinks []domain.OutboxSink
+
+func (s OutboxSinks) Publish(ctx context.Context, ev domain.OutboxEvent) error {
+	for _, sink := range s {
+		if err := sink.Publish(ctx, ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OutboxRelay delivers the events MessagesRepo records in the outbox to a
+// sink, at least once and in order per message.
+type OutboxRelay struct {
+	repo domain.OutboxRepo
+	sink domain.OutboxSink
+	cfg  OutboxRelayConfig
+}
+
+func NewOutboxRelay(repo domain.OutboxRepo, sink domain.OutboxSink, cfg OutboxRelayConfig) *OutboxRelay {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 24 * time.Hour
+	}
+	return &OutboxRelay{repo: repo, sink: sink, cfg: cfg}
+}
+
+// Run relays every Interval until ctx is done, draining the backlog in
+// batches, and prunes published events once an hour.
+func (o *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.cfg.Interval)
+	defer ticker.Stop()
+	var lastPrune time.Time
+	for {
+		o.drain(ctx)
+		if time.Since(lastPrune) >= time.Hour {
+			lastPrune = time.Now()
+			if n, err := o.repo.PruneOutbox(ctx, time.Now().Add(-o.cfg.Retention)); err != nil && ctx.Err() == nil {
+				log.Logger.ErrorContext(ctx, "prune outbox failed", "err", err)
+			} else if n > 0 {
+				log.Logger.InfoContext(ctx, "pruned outbox", "events", n)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain relays until nothing is due. Each batch holds at most one event per
+// message, so a message with several pending events takes several batches.
+func (o *OutboxRelay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := o.relay(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Logger.ErrorContext(ctx, "relay outbox failed", "err", err)
+			}
+			return
+		}
+		if n == 0 {
+			return
+		}
+	}
+}
+
+// relay publishes one claimed batch and returns its size. The events belong
+// to different messages, so they are published concurrently and one slow
+// publish only costs its own timeout. Events whose outcome can't be
+// recorded are claimed again once their lease runs out.
+func (o *OutboxRelay) relay(ctx context.Context) (int, error) {
+	events, err := o.repo.ClaimOutbox(ctx, o.cfg.BatchSize, o.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		published []int64
+	)
+	for _, ev := range events {
+		wg.Add(1)
+		go func(ev domain.OutboxEvent) {
+			defer wg.Done()
+			if o.publish(ctx, ev) {
+				mu.Lock()
+				published = append(published, ev.ID)
+				mu.Unlock()
+			}
+		}(ev)
+	}
+	wg.Wait()
+	if err := o.repo.MarkOutboxPublished(ctx, published); err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
+
+// publish reports whether ev reached the sink; a failed event is held back
+// for its backoff.
+func (o *OutboxRelay) publish(ctx context.Context, ev domain.OutboxEvent) bool {
+	err := o.sink.Publish(ctx, ev)
+	if err == nil {
+		metrics.OutboxEvents.WithLabelValues("published").Inc()
+		return true
+	}
+	metrics.OutboxEvents.WithLabelValues("failed").Inc()
+	log.Logger.WarnContext(ctx, "publish outbox event failed",
+		"outbox_id", ev.ID,
+		"msg_id", ev.MessageID,
+		"event", ev.Type,
+		"attempts", ev.Attempts+1,
+		"err", err,
+	)
+	next := time.Now().Add(backoff(o.cfg.Backoff, o.cfg.MaxBackoff, ev.Attempts+1))
+	if err := o.repo.RecordOutboxFailure(ctx, ev.ID, next, err); err != nil {
+		log.Logger.ErrorContext(ctx, "record outbox failure failed", "outbox_id", ev.ID, "err", err)
+	}
+	return false
+}
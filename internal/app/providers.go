@@ -0,0 +1,141 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// ProviderRegistry routes each message to the provider registered for its
+// channel. It satisfies domain.Provider so the Sender doesn't need to know
+// which channels exist.
+type ProviderRegistry struct {
+	providers map[string]domain.Provider
+
+	tenants   domain.TenantsRepo
+	build     ProviderFactory
+	tenantTTL time.Duration
+
+	mu          sync.Mutex
+	tenantCache map[string]*tenantProviders
+}
+
+// ProviderFactory builds the provider a tenant configured for channel.
+type ProviderFactory func(channel string, cfg domain.ProviderConfig) (domain.Provider, error)
+
+type tenantProviders struct {
+	fetched   time.Time
+	updatedAt time.Time
+	byChannel map[string]tenantProvider
+}
+
+type tenantProvider struct {
+	p   domain.Provider
+	err error
+}
+
+func NewProviderRegistry() *ProviderRegistry {
+	return &ProviderRegistry{providers: map[string]domain.Provider{}}
+}
+
+func (r *ProviderRegistry) Register(channel string, p domain.Provider) {
+	r.providers[channel] = p
+}
+
+// UseTenantProviders makes Send prefer a tenant's own provider for the
+// channels it configures. Tenant settings are re-read at most every ttl, and
+// providers are only rebuilt when the tenant changed.
+func (r *ProviderRegistry) UseTenantProviders(tenants domain.TenantsRepo, build ProviderFactory, ttl time.Duration) {
+	r.tenants = tenants
+	r.build = build
+	r.tenantTTL = ttl
+	r.tenantCache = map[string]*tenantProviders{}
+}
+
+func (r *ProviderRegistry) Channels() []string {
+	out := make([]string, 0, len(r.providers))
+	for ch := range r.providers {
+		out = append(out, ch)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (r *ProviderRegistry) Send(ctx context.Context, msg domain.Message) (id string, err error) {
+	channel := msg.Channel
+	if channel == "" {
+		channel = domain.ChannelSMS
+	}
+
+	ctx, span := tracing.Tracer.Start(ctx, "provider.send")
+	span.SetAttributes(attribute.String("message.channel", channel))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "provider send failed")
+		} else {
+			span.SetAttributes(attribute.String("provider.message_id", id))
+		}
+		span.End()
+	}()
+
+	if r.tenants != nil && msg.TenantID != "" {
+		p, ok, err := r.tenantProvider(ctx, msg.TenantID, channel)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			span.SetAttributes(attribute.String("provider.tenant", msg.TenantID))
+			return p.Send(ctx, msg)
+		}
+	}
+
+	p, ok := r.providers[channel]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedChannel, channel)
+	}
+	return p.Send(ctx, msg)
+}
+
+// tenantProvider returns the tenant's own provider for channel, if it has one.
+func (r *ProviderRegistry) tenantProvider(ctx context.Context, tenantID, channel string) (domain.Provider, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := r.tenantCache[tenantID]
+	if entry == nil || time.Since(entry.fetched) > r.tenantTTL {
+		t, err := r.tenants.GetTenant(ctx, tenantID)
+		if errors.Is(err, domain.ErrTenantNotFound) {
+			t = domain.Tenant{ID: tenantID}
+		} else if err != nil {
+			return nil, false, fmt.Errorf("load tenant %s: %w", tenantID, err)
+		}
+
+		if entry == nil || !entry.updatedAt.Equal(t.UpdatedAt) {
+			entry = &tenantProviders{updatedAt: t.UpdatedAt, byChannel: map[string]tenantProvider{}}
+			for ch, cfg := range t.Providers {
+				p, err := r.build(ch, cfg)
+				entry.byChannel[ch] = tenantProvider{p: p, err: err}
+			}
+			r.tenantCache[tenantID] = entry
+		}
+		entry.fetched = time.Now()
+	}
+
+	tp, ok := entry.byChannel[channel]
+	if !ok {
+		return nil, false, nil
+	}
+	if tp.err != nil {
+		return nil, false, fmt.Errorf("tenant %s %s provider: %w", tenantID, channel, tp.err)
+	}
+	return tp.p, true, nil
+}
@@ -2,11 +2,16 @@ package app
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/temo927/go-msg-dispatcher/internal/domain"
 	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/metrics"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 type Scheduler struct {
@@ -33,18 +38,18 @@ func (s *Scheduler) Start(_ context.Context) error {
 	defer s.mu.Unlock()
 
 	if s.running {
-		log.Logger.Info("scheduler already running")
-		return nil
+		return ErrAlreadyRunning
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	s.cancel = cancel
 	s.ticker = time.NewTicker(s.interval)
 	s.running = true
+	metrics.SchedulerRunning.Set(1)
 
 	log.Logger.Info("scheduler started", "interval", s.interval, "batch_size", s.batchSize)
 
-	go s.loop(ctx)
+	go s.loop(ctx, s.ticker)
 	return nil
 }
 
@@ -53,8 +58,7 @@ func (s *Scheduler) Stop() error {
 	defer s.mu.Unlock()
 
 	if !s.running {
-		log.Logger.Info("scheduler already stopped")
-		return nil
+		return ErrNotRunning
 	}
 
 	if s.ticker != nil {
@@ -66,6 +70,7 @@ func (s *Scheduler) Stop() error {
 	s.ticker = nil
 	s.cancel = nil
 	s.running = false
+	metrics.SchedulerRunning.Set(0)
 
 	log.Logger.Info("scheduler stopped")
 	return nil
@@ -77,9 +82,10 @@ func (s *Scheduler) IsRunning() bool {
 	return s.running
 }
 
-func (s *Scheduler) loop(ctx context.Context) {
+// loop gets its own ticker, as Stop clears s.ticker while it may still run.
+func (s *Scheduler) loop(ctx context.Context, ticker *time.Ticker) {
 	if err := s.process(ctx); err != nil {
-		log.Logger.Error("scheduler initial process failed", "err", err)
+		log.Logger.ErrorContext(ctx, "scheduler initial process failed", "err", err)
 	}
 
 	for {
@@ -87,29 +93,42 @@ func (s *Scheduler) loop(ctx context.Context) {
 		case <-ctx.Done():
 			log.Logger.Info("scheduler exiting")
 			return
-		case <-s.ticker.C:
+		case <-ticker.C:
 			if err := s.process(ctx); err != nil {
-				log.Logger.Error("scheduler tick failed", "err", err)
+				log.Logger.ErrorContext(ctx, "scheduler tick failed", "err", err)
 			}
 		}
 	}
 }
 
 func (s *Scheduler) process(ctx context.Context) error {
+	ctx, span := tracing.Tracer.Start(ctx, "scheduler.tick")
+	defer span.End()
+
 	msgs, err := s.repo.ClaimNextBatch(ctx, s.batchSize)
 	if err != nil {
-		log.Logger.Error("claim batch failed", "err", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "claim batch failed")
+		log.Logger.ErrorContext(ctx, "claim batch failed", "err", err)
 		return err
 	}
+	span.SetAttributes(attribute.Int("messages.claimed", len(msgs)))
 	if len(msgs) == 0 {
-		log.Logger.Info("no queued messages to process")
+		log.Logger.InfoContext(ctx, "no queued messages to process")
 		return nil
 	}
 	for _, m := range msgs {
-		if err := s.sender.Send(ctx, m); err != nil {
-			log.Logger.Error("send failed", "msg_id", m.ID, "err", err)
+		// Log the send under the id of the request that created the message.
+		msgCtx := ctx
+		if m.RequestID != nil {
+			msgCtx = log.WithRequestID(ctx, *m.RequestID)
+		}
+		if err := s.sender.Send(msgCtx, m); errors.Is(err, ErrSuppressed) {
+			log.Logger.InfoContext(msgCtx, "message suppressed", "msg_id", m.ID)
+		} else if err != nil {
+			log.Logger.ErrorContext(msgCtx, "send failed", "msg_id", m.ID, "err", err)
 		} else {
-			log.Logger.Info("message sent", "msg_id", m.ID)
+			log.Logger.InfoContext(msgCtx, "message sent", "msg_id", m.ID)
 		}
 	}
 	return nil
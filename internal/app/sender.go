@@ -7,48 +7,146 @@ import (
 	"time"
 
 	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/metrics"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Sender struct {
-	repo  domain.MessagesRepo
-	prov  domain.Provider
-	cache domain.Cache
-	cfg   SenderConfig
+	repo         domain.MessagesRepo
+	prov         domain.Provider
+	cache        domain.Cache
+	suppressions domain.SuppressionList
+	events       domain.EventPublisher
+	cfg          SenderConfig
 }
 
 type SenderConfig struct {
 	MaxRetries int
 }
 
-func NewSender(repo domain.MessagesRepo, prov domain.Provider, cache domain.Cache, cfg SenderConfig) *Sender {
+func NewSender(repo domain.MessagesRepo, prov domain.Provider, cache domain.Cache, suppressions domain.SuppressionList, cfg SenderConfig) *Sender {
 	return &Sender{
-		repo:  repo,
-		prov:  prov,
-		cache: cache,
-		cfg:   cfg,
+		repo:         repo,
+		prov:         prov,
+		cache:        cache,
+		suppressions: suppressions,
+		cfg:          cfg,
 	}
 }
 
-func (s *Sender) Send(ctx context.Context, msg domain.Message) error {
+// UseEvents publishes the outcome of every attempt (sent, retried, failed,
+// suppressed) to p.
+func (s *Sender) UseEvents(p domain.EventPublisher) {
+	s.events = p
+}
+
+// Send delivers one claimed message. Its span is a child of the scheduler
+// tick and links to the request that created the message.
+func (s *Sender) Send(ctx context.Context, msg domain.Message) (err error) {
+	opts := []trace.SpanStartOption{trace.WithAttributes(
+		attribute.String("message.id", msg.ID),
+		attribute.String("message.channel", msg.Channel),
+		attribute.String("tenant.id", msg.TenantID),
+		attribute.Int("message.retry_count", msg.RetryCount),
+	)}
+	if msg.TraceParent != nil {
+		if link, ok := tracing.LinkFromTraceParent(*msg.TraceParent); ok {
+			opts = append(opts, trace.WithLinks(link))
+		}
+	}
+	ctx, span := tracing.Tracer.Start(ctx, "message.send", opts...)
+	defer func() {
+		if err != nil && err != ErrSuppressed {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "send failed")
+		}
+		span.End()
+	}()
+
+	// Recipients may opt out between enqueue and send, so check again here.
+	if s.suppressions != nil {
+		suppressed, err := s.suppressions.IsSuppressed(ctx, msg.TenantID, msg.Channel, msg.Recipient)
+		if err != nil {
+			// Our own lookup failed, not the message: put it back for the
+			// next tick without using up one of its retries.
+			err = internalError{fmt.Errorf("suppression check failed: %v", err)}
+			if e := s.repo.Requeue(ctx, msg.ID, err); e != nil {
+				return fmt.Errorf("%v (requeue error: %v)", err, e)
+			}
+			lastError, code := err.Error(), domain.SendErrorCode(err)
+			msg.LastError, msg.LastErrorCode = &lastError, &code
+			msg.Status = "queued"
+			s.publish(ctx, domain.EventRetried, msg)
+			return err
+		}
+		if suppressed {
+			if err := s.repo.MarkSuppressed(ctx, msg.ID); err != nil {
+				return fmt.Errorf("mark suppressed failed: %v", err)
+			}
+			metrics.MessagesSuppressed.WithLabelValues(msg.Channel).Inc()
+			msg.Status = "suppressed"
+			s.publish(ctx, domain.EventSuppressed, msg)
+			return ErrSuppressed
+		}
+	}
+
 	providerID, err := s.prov.Send(ctx, msg)
 	if err != nil {
 	
 		if e := s.repo.MarkFailed(ctx, msg.ID, err, s.cfg.MaxRetries); e != nil {
 			return fmt.Errorf("provider send failed: %v (mark failed error: %v)", err, e)
 		}
+		s.recordFailure(ctx, msg, err)
 		return fmt.Errorf("provider send failed: %v", err)
 	}
 
 	if err := s.repo.MarkSent(ctx, msg.ID, providerID); err != nil {
 		return fmt.Errorf("mark sent failed: %v", err)
 	}
+	metrics.MessagesSent.WithLabelValues(msg.Channel).Inc()
+	msg.Status = "sent"
+	msg.ProviderMessageID = &providerID
+	s.publish(ctx, domain.EventSent, msg)
 
+	// The lookup key is a convenience; consumers that must not miss a send
+	// read the outbox, which MarkSent wrote in the same transaction and which
+	// also feeds the sent stream.
 	if s.cache != nil {
-		_ = s.cache.SetSentMeta(ctx, msg.ID, map[string]string{
+		if err := s.cache.SetSentMeta(ctx, msg.ID, map[string]string{
 			"messageId": providerID,
 			"sent_at":   time.Now().UTC().Format(time.RFC3339),
-		})
+		}); err != nil {
+			log.Logger.WarnContext(ctx, "cache sent meta failed", "msg_id", msg.ID, "err", err)
+		}
 	}
 
 	return nil
 }
+
+// recordFailure mirrors MarkFailed: the attempt that reaches MaxRetries fails
+// the message for good, earlier ones requeue it.
+func (s *Sender) recordFailure(ctx context.Context, msg domain.Message, cause error) {
+	msg.RetryCount++
+	lastError, code := cause.Error(), domain.SendErrorCode(cause)
+	msg.LastError, msg.LastErrorCode = &lastError, &code
+
+	if msg.RetryCount >= s.cfg.MaxRetries {
+		metrics.MessagesFailed.WithLabelValues(msg.Channel).Inc()
+		msg.Status = "failed"
+		s.publish(ctx, domain.EventFailed, msg)
+		return
+	}
+	metrics.MessagesRetried.WithLabelValues(msg.Channel).Inc()
+	msg.Status = "queued"
+	s.publish(ctx, domain.EventRetried, msg)
+}
+
+func (s *Sender) publish(ctx context.Context, typ string, msg domain.Message) {
+	if s.events != nil {
+		s.events.Publish(ctx, domain.NewMessageEvent(typ, msg))
+	}
+}
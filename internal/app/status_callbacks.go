@@ -0,0 +1,184 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/metrics"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+type StatusCallbackConfig struct {
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	// Backoff is the delay before the first retry; it doubles with every
+	// further attempt, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Lease is how long a claimed batch is hidden from other replicas; it
+	// must outlast one attempt.
+	Lease time.Duration
+}
+
+// StatusCallbacks delivers queued status callbacks on its own schedule, so a
+// slow or broken receiver never holds up message sending.
+// Each callback is signed with its tenant's own secrets.
+type StatusCallbacks struct {
+	repo    domain.StatusCallbacksRepo
+	tenants domain.TenantsRepo
+	poster  domain.CallbackPoster
+	cfg     StatusCallbackConfig
+}
+
+func NewStatusCallbacks(repo domain.StatusCallbacksRepo, tenants domain.TenantsRepo, poster domain.CallbackPoster, cfg StatusCallbackConfig) *StatusCallbacks {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 20
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Hour
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+	return &StatusCallbacks{repo: repo, tenants: tenants, poster: poster, cfg: cfg}
+}
+
+// Run delivers due callbacks every Interval until ctx is done.
+func (c *StatusCallbacks) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.process(ctx); err != nil && ctx.Err() == nil {
+			log.Logger.ErrorContext(ctx, "status callback tick failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *StatusCallbacks) process(ctx context.Context) error {
+	cbs, err := c.repo.ClaimDueCallbacks(ctx, c.cfg.BatchSize, c.cfg.Lease)
+	if err != nil {
+		return fmt.Errorf("claim callbacks: %w", err)
+	}
+
+	// Secrets are looked up once per tenant and tick, so a rotation takes
+	// effect on the next tick.
+	secrets := make(map[string][]string)
+	for _, cb := range cbs {
+		if _, ok := secrets[cb.TenantID]; ok {
+			continue
+		}
+		t, err := c.tenants.GetTenant(ctx, cb.TenantID)
+		if err != nil {
+			// The lease runs out and the batch is claimed again.
+			return fmt.Errorf("load tenant %s: %w", cb.TenantID, err)
+		}
+		secrets[cb.TenantID] = t.CallbackSigningSecrets
+	}
+
+	// Receivers are independent, so one slow receiver only costs its own
+	// timeout.
+	var wg sync.WaitGroup
+	for _, cb := range cbs {
+		wg.Add(1)
+		go func(cb domain.StatusCallback) {
+			defer wg.Done()
+			c.deliver(ctx, cb, secrets[cb.TenantID])
+		}(cb)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (c *StatusCallbacks) deliver(ctx context.Context, cb domain.StatusCallback, secrets []string) {
+	ctx, span := tracing.Tracer.Start(ctx, "status_callback.deliver")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("message.id", cb.MessageID),
+		attribute.Int("status_callback.attempt", cb.Attempts+1),
+	)
+
+	start := time.Now()
+	code, err := c.poster.Post(ctx, cb.URL, secrets, cb.Payload)
+	attempt := domain.CallbackAttempt{
+		Attempt:     cb.Attempts + 1,
+		Duration:    time.Since(start),
+		AttemptedAt: start,
+	}
+	if code != 0 {
+		attempt.StatusCode = &code
+		span.SetAttributes(attribute.Int("http.response.status_code", code))
+	}
+	if err == nil && (code < 200 || code >= 300) {
+		err = fmt.Errorf("unexpected status %d", code)
+	}
+
+	cb.Attempts = attempt.Attempt
+	cb.NextAttemptAt = time.Now()
+	var result string
+	switch {
+	case err == nil:
+		cb.Status = domain.CallbackDelivered
+		cb.LastError = nil
+		result = "delivered"
+	case cb.Attempts >= c.cfg.MaxAttempts:
+		cb.Status = domain.CallbackFailed
+		result = "failed"
+	default:
+		cb.Status = domain.CallbackPending
+		cb.NextAttemptAt = time.Now().Add(backoff(c.cfg.Backoff, c.cfg.MaxBackoff, cb.Attempts))
+		result = "retried"
+	}
+	if err != nil {
+		msg := err.Error()
+		attempt.Error = &msg
+		cb.LastError = &msg
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "status callback failed")
+	}
+	metrics.StatusCallbacks.WithLabelValues(result).Inc()
+
+	if err := c.repo.RecordCallbackAttempt(ctx, cb, attempt); err != nil {
+		// The lease runs out and the callback is attempted again.
+		log.Logger.ErrorContext(ctx, "record status callback attempt failed", "msg_id", cb.MessageID, "err", err)
+		return
+	}
+	if err != nil {
+		log.Logger.WarnContext(ctx, "status callback failed",
+			"msg_id", cb.MessageID,
+			"attempt", cb.Attempts,
+			"result", result,
+			"err", err,
+		)
+	}
+}
+
+// backoff returns the delay after the given failed attempt: base, doubled
+// for every attempt after the first, capped at max.
+func backoff(base, max time.Duration, attempts int) time.Duration {
+	d := base
+	for i := 1; i < attempts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
@@ -0,0 +1,47 @@
+package domain
+
+import "errors"
+
+var (
+	ErrMessageNotFound  = errors.New("message not found")
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrTemplateExists   = errors.New("template name already exists")
+
+	ErrSuppressionNotFound = errors.New("suppression not found")
+	ErrAPIKeyNotFound      = errors.New("api key not found")
+
+	ErrTenantNotFound = errors.New("tenant not found")
+	ErrTenantExists   = errors.New("tenant already exists")
+	ErrQuotaExceeded  = errors.New("daily quota exceeded")
+
+	// ErrAmbiguousReport is returned for a delivery report whose provider
+	// message id matches more than one message.
+	ErrAmbiguousReport = errors.New("provider_message_id matches more than one message")
+	// ErrReportBeforeSend is returned for a delivery report dated before the
+	// message was sent.
+	ErrReportBeforeSend = errors.New("delivered_at is before the message was sent")
+)
+
+// Stable codes for why a send failed, reported in message events and status
+// callbacks. The error text can name internal hosts and only ever goes to the
+// message's last_error.
+const (
+	SendErrorProviderRejected    = "provider_rejected"
+	SendErrorProviderUnavailable = "provider_unavailable"
+	SendErrorInternal            = "internal_error"
+)
+
+// ErrorCoder is implemented by errors that know their send error code.
+type ErrorCoder interface {
+	ErrorCode() string
+}
+
+// SendErrorCode maps err to a send error code. Errors without one are taken
+// as the provider being unreachable, e.g. dial errors and timeouts.
+func SendErrorCode(err error) string {
+	var coder ErrorCoder
+	if errors.As(err, &coder) {
+		return coder.ErrorCode()
+	}
+	return SendErrorProviderUnavailable
+}
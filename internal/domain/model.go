@@ -2,15 +2,240 @@ package domain
 
 import "time"
 
+const (
+	ChannelSMS   = "sms"
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+)
+
 type Message struct {
 	ID                 string
-	ToPhone            string
+	TenantID           string
+	Channel            string
+	Recipient          string
+	Subject            *string
 	Content            string
+	Encoding           *string
+	Segments           *int
 	Status             string
 	RetryCount         int
 	ProviderMessageID  *string
 	LastError          *string
+	// LastErrorCode is the SendErrorCode of LastError.
+	LastErrorCode      *string
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
 	SentAt             *time.Time
+	DeliveredAt        *time.Time
+	DeliveryErrorCode  *string
+	TemplateID         *string
+	TemplateVersion    *int
+	// TraceParent is the W3C trace context of the creating request.
+	TraceParent        *string
+	// RequestID is the X-Request-ID of the creating request.
+	RequestID          *string
+	// BatchID is a client-chosen label grouping messages, e.g. a campaign.
+	BatchID            *string
+	// CallbackURL receives a StatusCallback when the message is sent,
+	// suppressed or fails for good.
+	CallbackURL        *string
+}
+
+// Template is one version of a named message template, with a body per locale.
+type Template struct {
+	ID            string
+	TenantID      string
+	Name          string
+	DefaultLocale string
+	Version       int
+	Bodies        map[string]string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// DeliveryReport is a provider receipt telling whether a sent message
+// actually reached the handset.
+type DeliveryReport struct {
+	ProviderMessageID string
+	// Channel, if set, narrows the match to messages sent on it.
+	Channel           string
+	Delivered         bool
+	At                time.Time
+	ErrorCode         string
+}
+
+// Suppression blocks all messages on a channel to a recipient, e.g. after
+// they replied STOP or were flagged by compliance.
+type Suppression struct {
+	TenantID  string
+	Channel   string
+	Recipient string
+	Reason    string
+	CreatedAt time.Time
+}
+
+const (
+	ScopeMessagesWrite  = "messages:write"
+	ScopeMessagesRead   = "messages:read"
+	ScopeSchedulerAdmin = "scheduler:admin"
+	ScopeKeysAdmin      = "keys:admin"
+	// ScopeTenantsAdmin manages tenants and reaches across tenant boundaries.
+	ScopeTenantsAdmin = "tenants:admin"
+	// ScopeOpsAdmin changes runtime settings such as the log level.
+	ScopeOpsAdmin = "ops:admin"
+)
+
+// Scopes lists every scope an API key can be granted.
+var Scopes = []string{ScopeMessagesWrite, ScopeMessagesRead, ScopeSchedulerAdmin, ScopeKeysAdmin, ScopeTenantsAdmin, ScopeOpsAdmin}
+
+// GlobalScopes act on the whole deployment rather than one tenant, so only
+// tenant admins may grant them.
+var GlobalScopes = []string{ScopeTenantsAdmin, ScopeSchedulerAdmin, ScopeOpsAdmin}
+
+type APIKey struct {
+	ID         string
+	TenantID   string
+	Name       string
+	Prefix     string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultTenant owns everything created before tenants existed, and all
+// requests while authentication is disabled.
+const DefaultTenant = "default"
+
+// Tenant is a team sharing the dispatcher. Its messages are counted against
+// DailyQuota (nil means unlimited) and may be routed to its own providers.
+type Tenant struct {
+	ID         string
+	Name       string
+	DailyQuota *int
+	// Providers overrides the global provider per channel.
+	Providers map[string]ProviderConfig
+	// CallbackSigningSecrets sign the tenant's status callbacks; callback_url
+	// is refused while there are none. Several allow rotation.
+	CallbackSigningSecrets []string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ProviderConfig points a channel at a tenant's own webhook provider; unset
+// fields fall back to the global webhook settings.
+type ProviderConfig struct {
+	URL            string            `json:"url"`
+	AuthHeader     string            `json:"auth_header,omitempty"`
+	AuthValue      string            `json:"auth_value,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	BodyTemplate   string            `json:"body_template,omitempty"`
+	ResponseIDPath string            `json:"response_id_path,omitempty"`
+}
+
+// Message lifecycle event types.
+const (
+	EventCreated     = "created"
+	EventClaimed     = "claimed"
+	EventSent        = "sent"
+	EventRetried     = "retried"
+	EventFailed      = "failed"
+	EventSuppressed  = "suppressed"
+	EventDelivered   = "delivered"
+	EventUndelivered = "undelivered"
+)
+
+// EventTypes lists every MessageEvent type.
+var EventTypes = []string{
+	EventCreated, EventClaimed, EventSent, EventRetried, EventFailed,
+	EventSuppressed, EventDelivered, EventUndelivered,
+}
+
+// MessageEvent is one step of a message's lifecycle, as streamed to clients
+// following its progress. Status is the message's status after the step.
+type MessageEvent struct {
+	Type              string    `json:"type"`
+	MessageID         string    `json:"message_id"`
+	TenantID          string    `json:"tenant_id"`
+	Channel           string    `json:"channel"`
+	Recipient         string    `json:"recipient"`
+	Status            string    `json:"status"`
+	BatchID           *string   `json:"batch_id,omitempty"`
+	RetryCount        int       `json:"retry_count"`
+	ProviderMessageID *string   `json:"provider_message_id,omitempty"`
+	ErrorCode         *string   `json:"error_code,omitempty"`
+	At                time.Time `json:"at"`
+}
+
+// NewMessageEvent describes m as it is after the step typ.
+func NewMessageEvent(typ string, m Message) MessageEvent {
+	return MessageEvent{
+		Type:              typ,
+		MessageID:         m.ID,
+		TenantID:          m.TenantID,
+		Channel:           m.Channel,
+		Recipient:         m.Recipient,
+		Status:            m.Status,
+		BatchID:           m.BatchID,
+		RetryCount:        m.RetryCount,
+		ProviderMessageID: m.ProviderMessageID,
+		ErrorCode:         m.LastErrorCode,
+		At:                time.Now().UTC(),
+	}
+}
+
+// Status callback states.
+const (
+	CallbackPending   = "pending"
+	CallbackDelivered = "delivered"
+	CallbackFailed    = "failed"
+)
+
+// StatusCallback tells a message's creator its final status by POSTing
+// Payload, a MessageEvent, to URL until it is accepted or attempts run out.
+type StatusCallback struct {
+	ID            string
+	MessageID     string
+	TenantID      string
+	URL           string
+	Payload       []byte
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     *string
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+	// Log holds the attempts so far, when loaded.
+	Log []CallbackAttempt
+}
+
+// CallbackAttempt is one entry of a status callback's delivery log.
+type CallbackAttempt struct {
+	Attempt     int
+	StatusCode  *int
+	Error       *string
+	Duration    time.Duration
+	AttemptedAt time.Time
+}
+
+// OutboxEvent is a MessageEvent recorded in the same transaction as the
+// status change it describes, waiting to be relayed downstream. IDs grow
+// with every change, so they order the events of a message.
+type OutboxEvent struct {
+	ID        int64
+	MessageID string
+	TenantID  string
+	Type      string
+	Payload   []byte
+	CreatedAt time.Time
+	Attempts  int
 }
@@ -1,19 +1,135 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type MessagesRepo interface {
 	ClaimNextBatch(ctx context.Context, limit int) ([]Message, error)
 	MarkSent(ctx context.Context, id, providerMessageID string) error
 	MarkFailed(ctx context.Context, id string, err error, maxRetries int) error
-	ListSent(ctx context.Context, limit, offset int) ([]Message, error)
-	Create(ctx context.Context, to, content string) (Message, error)
+	MarkSuppressed(ctx context.Context, id string) error
+	// Requeue puts a claimed message back in the queue without counting an
+	// attempt, for failures that say nothing about the message itself.
+	Requeue(ctx context.Context, id string, cause error) error
+	ApplyDeliveryReport(ctx context.Context, report DeliveryReport) (Message, error)
+	// ListSent lists sent messages of tenantID, or of every tenant if it is empty.
+	ListSent(ctx context.Context, tenantID string, limit, offset int) ([]Message, error)
+	// GetMessage returns message id of tenantID, or of any tenant if it is empty.
+	GetMessage(ctx context.Context, tenantID, id string) (Message, error)
+	// ListByRequestID lists the messages of tenantID (or of every tenant if it
+	// is empty) created by the request with the given X-Request-ID.
+	ListByRequestID(ctx context.Context, tenantID, requestID string) ([]Message, error)
+	Create(ctx context.Context, msg Message) (Message, error)
+	// CreateWithinQuota creates msg unless its tenant already created quota
+	// messages since UTC midnight, in which case it returns ErrQuotaExceeded.
+	CreateWithinQuota(ctx context.Context, msg Message, quota int) (Message, error)
+}
+
+// TemplatesRepo methods taking a tenantID only see that tenant's templates,
+// or every tenant's if it is empty.
+type TemplatesRepo interface {
+	CreateTemplate(ctx context.Context, t Template) (Template, error)
+	// AddTemplateVersion stores bodies as the next version of template id.
+	AddTemplateVersion(ctx context.Context, tenantID, id, defaultLocale string, bodies map[string]string) (Template, error)
+	// GetTemplate returns the given version, or the latest one if version is 0.
+	GetTemplate(ctx context.Context, tenantID, id string, version int) (Template, error)
+	ListTemplates(ctx context.Context, tenantID string, limit, offset int) ([]Template, error)
+	DeleteTemplate(ctx context.Context, tenantID, id string) error
+}
+
+// SuppressionList tells whether tenantID suppressed a recipient. Tenants
+// keep separate lists.
+type SuppressionList interface {
+	IsSuppressed(ctx context.Context, tenantID, channel, recipient string) (bool, error)
+}
+
+type SuppressionsRepo interface {
+	SuppressionList
+	AddSuppression(ctx context.Context, s Suppression) (Suppression, error)
+	RemoveSuppression(ctx context.Context, tenantID, channel, recipient string) error
+	// ListSuppressions lists tenantID's suppressions, or every tenant's if it
+	// is empty.
+	ListSuppressions(ctx context.Context, tenantID string, limit, offset int) ([]Suppression, error)
+}
+
+type APIKeysRepo interface {
+	CreateAPIKey(ctx context.Context, key APIKey, hash string) (APIKey, error)
+	// FindAPIKeyByHash returns the active (not revoked) key with this hash.
+	FindAPIKeyByHash(ctx context.Context, hash string) (APIKey, error)
+	TouchAPIKey(ctx context.Context, id string) error
+	// ListAPIKeys and RevokeAPIKey are limited to tenantID unless it is empty.
+	ListAPIKeys(ctx context.Context, tenantID string) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, tenantID, id string) error
+}
+
+type TenantsRepo interface {
+	CreateTenant(ctx context.Context, t Tenant) (Tenant, error)
+	GetTenant(ctx context.Context, id string) (Tenant, error)
+	ListTenants(ctx context.Context, limit, offset int) ([]Tenant, error)
+	// UpdateTenant replaces the name, quota and provider overrides of t.ID.
+	UpdateTenant(ctx context.Context, t Tenant) (Tenant, error)
 }
 
 type Provider interface {
 	Send(ctx context.Context, msg Message) (string, error)
 }
 
+// EventPublisher broadcasts message lifecycle events. Publishing is best
+// effort: events are for watching progress, the database stays the record.
+type EventPublisher interface {
+	Publish(ctx context.Context, ev MessageEvent)
+}
+
+// StatusCallbacksRepo is the retry queue and delivery log of status
+// callbacks. Callbacks are queued by the MessagesRepo, together with the
+// status change they report.
+type StatusCallbacksRepo interface {
+	// ClaimDueCallbacks leases up to limit pending callbacks whose next
+	// attempt is due, hiding them from other replicas for lease.
+	ClaimDueCallbacks(ctx context.Context, limit int, lease time.Duration) ([]StatusCallback, error)
+	// RecordCallbackAttempt logs a and stores the status, attempt count,
+	// next attempt time and last error of cb.
+	RecordCallbackAttempt(ctx context.Context, cb StatusCallback, a CallbackAttempt) error
+	// ListCallbacks returns the callbacks of message messageID with their
+	// delivery log, limited to tenantID unless it is empty.
+	ListCallbacks(ctx context.Context, tenantID, messageID string) ([]StatusCallback, error)
+}
+
+// CallbackPoster POSTs a status callback signed with every one of secrets.
+// A response, whatever its status, is not an error.
+type CallbackPoster interface {
+	Post(ctx context.Context, url string, secrets []string, payload []byte) (statusCode int, err error)
+}
+
+// OutboxRepo hands recorded status changes to a relay.
+type OutboxRepo interface {
+	// ClaimOutbox leases up to limit events, each the oldest unpublished
+	// event of its message and due for an attempt, hiding them from other
+	// replicas for lease.
+	ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]OutboxEvent, error)
+	MarkOutboxPublished(ctx context.Context, ids []int64) error
+	// RecordOutboxFailure counts a failed attempt and holds the event, and
+	// with it the later events of its message, until nextAttemptAt.
+	RecordOutboxFailure(ctx context.Context, id int64, nextAttemptAt time.Time, cause error) error
+	// PruneOutbox deletes events published before before.
+	PruneOutbox(ctx context.Context, before time.Time) (int64, error)
+}
+
+// OutboxSink receives relayed events at least once, in order per message.
+type OutboxSink interface {
+	Publish(ctx context.Context, ev OutboxEvent) error
+}
+
 type Cache interface {
 	SetSentMeta(ctx context.Context, msgID string, meta map[string]string) error
 }
+
+// Deduplicator tracks recently enqueued messages so identical ones can be
+// caught within a time window.
+type Deduplicator interface {
+	ClaimDedup(ctx context.Context, key string, ttl time.Duration) (existingID string, claimed bool, err error)
+	CompleteDedup(ctx context.Context, key, msgID string) error
+	ReleaseDedup(ctx context.Context, key string) error
+}
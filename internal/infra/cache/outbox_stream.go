@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+// OutboxStream implements domain.OutboxSink by appending events to a Redis
+// Stream. Each entry carries the outbox id, on which consumers de-duplicate
+// redeliveries.
+type OutboxStream struct {
+	cache  *Cache
+	stream StreamConfig
+}
+
+func NewOutboxStream(c *Cache, stream StreamConfig) *OutboxStream {
+	return &OutboxStream{cache: c, stream: stream}
+}
+
+func (s *OutboxStream) Publish(ctx context.Context, ev domain.OutboxEvent) error {
+	return s.cache.client.XAdd(ctx, s.stream.xadd([]any{
+		"outbox_id", strconv.FormatInt(ev.ID, 10),
+		"message_id", ev.MessageID,
+		"tenant_id", ev.TenantID,
+		"type", ev.Type,
+		"payload", string(ev.Payload),
+	})).Err()
+}
+
+// SentStream implements domain.OutboxSink for the sent stream: fed by the
+// outbox relay, it appends every sent message (msg_id, messageId, sent_at)
+// at least once and skips all other events. Like OutboxStream's, entries
+// carry the outbox id to de-duplicate on.
+type SentStream struct {
+	cache  *Cache
+	stream StreamConfig
+}
+
+func NewSentStream(c *Cache, stream StreamConfig) *SentStream {
+	return &SentStream{cache: c, stream: stream}
+}
+
+func (s *SentStream) Publish(ctx context.Context, ev domain.OutboxEvent) error {
+	if ev.Type != domain.EventSent {
+		return nil
+	}
+	var sent domain.MessageEvent
+	if err := json.Unmarshal(ev.Payload, &sent); err != nil {
+		return fmt.Errorf("decode sent event: %w", err)
+	}
+	values := []any{
+		"outbox_id", strconv.FormatInt(ev.ID, 10),
+		"msg_id", ev.MessageID,
+	}
+	if sent.ProviderMessageID != nil {
+		values = append(values, "messageId", *sent.ProviderMessageID)
+	}
+	values = append(values, "sent_at", sent.At.UTC().Format(time.RFC3339))
+	return s.cache.client.XAdd(ctx, s.stream.xadd(values)).Err()
+}
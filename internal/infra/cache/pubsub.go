@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+)
+
+// Publish sends payload to every subscriber of channel, on any replica.
+func (c *Cache) Publish(ctx context.Context, channel string, payload []byte) error {
+	return c.client.Publish(ctx, channel, payload).Err()
+}
+
+// Subscribe calls handle with every payload published to channel until ctx
+// is done or the connection breaks; the caller decides whether to retry.
+func (c *Cache) Subscribe(ctx context.Context, channel string, handle func(payload []byte)) error {
+	sub := c.client.Subscribe(ctx, channel)
+	defer sub.Close()
+
+	// Wait for Redis to confirm, so an unreachable server is reported
+	// instead of leaving the caller waiting for events that never come.
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("subscribe %s: %w", channel, err)
+	}
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case m, ok := <-ch:
+			if !ok {
+				return fmt.Errorf("subscription to %s closed", channel)
+			}
+			handle([]byte(m.Payload))
+		}
+	}
+}
@@ -3,6 +3,8 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -31,3 +33,83 @@ func (c *Cache) SetSentMeta(ctx context.Context, msgID string, meta map[string]s
 	}
 	return c.client.Set(ctx, "msg:"+msgID+":meta", data, c.ttl).Err()
 }
+
+func (c *Cache) GetToken(ctx context.Context, key string) (string, time.Time, bool, error) {
+	data, err := c.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return "", time.Time{}, false, nil
+	}
+	if err != nil {
+		return "", time.Time{}, false, err
+	}
+	var t cachedToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		return "", time.Time{}, false, err
+	}
+	return t.Token, t.ExpiresAt, true, nil
+}
+
+func (c *Cache) SetToken(ctx context.Context, key, token string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(cachedToken{Token: token, ExpiresAt: expiresAt})
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, key, data, ttl).Err()
+}
+
+type cachedToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+const dedupPending = "pending"
+
+// dedupClaimAttempts bounds the SETNX/GET rounds of ClaimDedup; a key only
+// vanishes between the two when it expires or is released concurrently.
+const dedupClaimAttempts = 3
+
+// ClaimDedup reserves key for ttl. If the key is already held it returns the
+// id of the message that holds it ("" while that message is still being
+// created) and claimed=false.
+func (c *Cache) ClaimDedup(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	for i := 0; i < dedupClaimAttempts; i++ {
+		ok, err := c.client.SetNX(ctx, key, dedupPending, ttl).Result()
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			return "", true, nil
+		}
+		existing, err := c.client.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			// Expired or released between SETNX and GET; try to claim it.
+			continue
+		}
+		if err != nil {
+			return "", false, err
+		}
+		if existing == dedupPending {
+			existing = ""
+		}
+		return existing, false, nil
+	}
+	return "", false, fmt.Errorf("dedup key %s kept disappearing", key)
+}
+
+// CompleteDedup stores the id of the message created under a claimed key.
+func (c *Cache) CompleteDedup(ctx context.Context, key, msgID string) error {
+	return c.client.SetArgs(ctx, key, msgID, redis.SetArgs{KeepTTL: true, Mode: "XX"}).Err()
+}
+
+// ReleaseDedup frees a claimed key whose message could not be created.
+func (c *Cache) ReleaseDedup(ctx context.Context, key string) error {
+	return c.client.Del(ctx, key).Err()
+}
+
+func (c *Cache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// StreamConfig describes a Redis Stream the dispatcher appends to.
+type StreamConfig struct {
+	Name string
+	// MaxLen trims the stream to about this many entries on every append
+	// (XADD MAXLEN ~); 0 keeps every entry. Trimming ignores consumer
+	// groups: entries a lagging group hasn't read yet are dropped all the
+	// same, so size it for the longest consumer outage to ride out.
+	MaxLen int64
+	// Group is a consumer group EnsureStream creates, so entries added
+	// before its consumers first start aren't missed.
+	Group string
+}
+
+func (s StreamConfig) xadd(values []any) *redis.XAddArgs {
+	return &redis.XAddArgs{
+		Stream: s.Name,
+		MaxLen: s.MaxLen,
+		Approx: s.MaxLen > 0,
+		Values: values,
+	}
+}
+
+// EnsureStream creates the stream and its consumer group if they are
+// missing. The group starts at the beginning of the stream.
+func (c *Cache) EnsureStream(ctx context.Context, s StreamConfig) error {
+	if s.Group == "" {
+		return nil
+	}
+	err := c.client.XGroupCreateMkStream(ctx, s.Name, s.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("create group %s on %s: %w", s.Group, s.Name, err)
+	}
+	return nil
+}
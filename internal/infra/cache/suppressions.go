@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+)
+
+// SuppressionCache puts a Redis read-through cache in front of the suppression
+// list, since every create and every send looks a recipient up. Writes go to
+// the underlying repo first and then overwrite the cached entry with the new
+// state; lookups only fill entries that are absent, so a lookup racing a write
+// can never put back the state from before it.
+type SuppressionCache struct {
+	domain.SuppressionsRepo
+	cache *Cache
+	ttl   time.Duration
+}
+
+func NewSuppressionCache(repo domain.SuppressionsRepo, c *Cache, ttl time.Duration) *SuppressionCache {
+	return &SuppressionCache{SuppressionsRepo: repo, cache: c, ttl: ttl}
+}
+
+func (s *SuppressionCache) IsSuppressed(ctx context.Context, tenantID, channel, recipient string) (bool, error) {
+	key := suppressionKey(tenantID, channel, recipient)
+	if v, err := s.cache.client.Get(ctx, key).Result(); err == nil {
+		return v == "1", nil
+	}
+
+	suppressed, err := s.SuppressionsRepo.IsSuppressed(ctx, tenantID, channel, recipient)
+	if err != nil {
+		return false, err
+	}
+	if err := s.cache.client.SetNX(ctx, key, cachedFlag(suppressed), s.ttl).Err(); err != nil {
+		log.Logger.ErrorContext(ctx, "suppression cache set failed", "err", err)
+	}
+	return suppressed, nil
+}
+
+// AddSuppression fails if the cache cannot be updated: the recipient could
+// otherwise keep receiving messages until a cached "not suppressed" expires.
+// Adding is idempotent, so the caller can simply retry.
+func (s *SuppressionCache) AddSuppression(ctx context.Context, sup domain.Suppression) (domain.Suppression, error) {
+	out, err := s.SuppressionsRepo.AddSuppression(ctx, sup)
+	if err != nil {
+		return domain.Suppression{}, err
+	}
+	if err := s.store(ctx, sup.TenantID, sup.Channel, sup.Recipient, true); err != nil {
+		return domain.Suppression{}, err
+	}
+	return out, nil
+}
+
+// RemoveSuppression also clears the cache when the entry was already gone, so
+// retrying after a failed cache write fixes it.
+func (s *SuppressionCache) RemoveSuppression(ctx context.Context, tenantID, channel, recipient string) error {
+	err := s.SuppressionsRepo.RemoveSuppression(ctx, tenantID, channel, recipient)
+	if err != nil && !errors.Is(err, domain.ErrSuppressionNotFound) {
+		return err
+	}
+	if err := s.store(ctx, tenantID, channel, recipient, false); err != nil {
+		return err
+	}
+	return err
+}
+
+func (s *SuppressionCache) store(ctx context.Context, tenantID, channel, recipient string, suppressed bool) error {
+	if err := s.cache.client.Set(ctx, suppressionKey(tenantID, channel, recipient), cachedFlag(suppressed), s.ttl).Err(); err != nil {
+		return fmt.Errorf("update suppression cache: %w", err)
+	}
+	return nil
+}
+
+func cachedFlag(suppressed bool) string {
+	if suppressed {
+		return "1"
+	}
+	return "0"
+}
+
+func suppressionKey(tenantID, channel, recipient string) string {
+	return "suppression:" + tenantID + ":" + channel + ":" + recipient
+}
@@ -0,0 +1,183 @@
+// Package events fans message lifecycle events out to the SSE clients of
+// every replica.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+)
+
+// Transport carries published events between replicas, e.g. Redis pub/sub.
+type Transport interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	Subscribe(ctx context.Context, channel string, handle func(payload []byte)) error
+}
+
+// subscriberBuffer is how many events a slow client may fall behind before
+// it is disconnected.
+const subscriberBuffer = 256
+
+// Bus implements domain.EventPublisher. Events always travel through the
+// transport, even to local subscribers, so each replica delivers every event
+// exactly once no matter which replica published it.
+type Bus struct {
+	transport Transport
+	channel   string
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func NewBus(transport Transport, channel string) *Bus {
+	return &Bus{
+		transport: transport,
+		channel:   channel,
+		subs:      map[*Subscription]struct{}{},
+	}
+}
+
+// Publish never blocks the caller on subscribers; failures are only logged.
+func (b *Bus) Publish(ctx context.Context, ev domain.MessageEvent) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		log.Logger.ErrorContext(ctx, "encode message event failed", "err", err)
+		return
+	}
+	if err := b.transport.Publish(ctx, b.channel, payload); err != nil {
+		log.Logger.WarnContext(ctx, "publish message event failed",
+			"msg_id", ev.MessageID,
+			"event", ev.Type,
+			"err", err,
+		)
+	}
+}
+
+// Run receives events from the transport and hands them to local
+// subscribers until ctx is done, resubscribing after connection failures.
+func (b *Bus) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		err := b.transport.Subscribe(ctx, b.channel, b.deliver)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Logger.Warn("event subscription lost, retrying", "err", err, "in", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, 30*time.Second)
+	}
+}
+
+func (b *Bus) deliver(payload []byte) {
+	var ev domain.MessageEvent
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		log.Logger.Warn("ignoring malformed message event", "err", err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		if !s.filter.Match(ev) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			// Rather than silently skipping events, end the stream; the
+			// client reconnects and re-reads current state.
+			s.lagged = true
+			delete(b.subs, s)
+			close(s.ch)
+		}
+	}
+}
+
+// Filter selects the events a subscriber receives. Empty fields match
+// everything.
+type Filter struct {
+	TenantID  string
+	Types     []string
+	Recipient string
+	BatchID   string
+}
+
+func (f Filter) Match(ev domain.MessageEvent) bool {
+	if f.TenantID != "" && ev.TenantID != f.TenantID {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, ev.Type) {
+		return false
+	}
+	if f.Recipient != "" && ev.Recipient != f.Recipient {
+		return false
+	}
+	if f.BatchID != "" && (ev.BatchID == nil || *ev.BatchID != f.BatchID) {
+		return false
+	}
+	return true
+}
+
+// Subscription receives matching events on C. C is closed when the
+// subscriber falls too far behind or when the subscription or bus is closed.
+type Subscription struct {
+	C <-chan domain.MessageEvent
+
+	ch     chan domain.MessageEvent
+	filter Filter
+	bus    *Bus
+	lagged bool
+}
+
+func (b *Bus) Subscribe(f Filter) *Subscription {
+	ch := make(chan domain.MessageEvent, subscriberBuffer)
+	s := &Subscription{C: ch, ch: ch, filter: f, bus: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Close ends every subscription, so open streams don't hold up a server
+// shutdown.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Lagged reports, once C is closed, whether it was because the subscriber
+// didn't keep up.
+func (s *Subscription) Lagged() bool {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.lagged
+}
+
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subs[s]; ok {
+		delete(s.bus.subs, s)
+		close(s.ch)
+	}
+}
@@ -1,17 +1,105 @@
 package log
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Level is shared by every logger built here, so changing it at runtime
+// takes effect immediately.
+var Level = new(slog.LevelVar)
+
+var Logger = New(os.Stdout, Config{Redact: true})
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
 )
 
-var Logger = newLogger()
+type Config struct {
+	Format string
+	// Redact masks phone numbers, email addresses and message content.
+	Redact bool
+}
+
+// New builds a logger writing to w that adds the request and trace ids found
+// in the context of *Context calls.
+func New(w io.Writer, cfg Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: Level}
+	if cfg.Redact {
+		opts.ReplaceAttr = redactAttr
+	}
+
+	var h slog.Handler
+	if cfg.Format == FormatJSON {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(contextHandler{h})
+}
+
+// Configure replaces Logger according to the given format and level.
+func Configure(cfg Config, level string) error {
+	if cfg.Format != "" && cfg.Format != FormatText && cfg.Format != FormatJSON {
+		return fmt.Errorf("unsupported log format %q", cfg.Format)
+	}
+	if err := SetLevel(level); err != nil {
+		return err
+	}
+	Logger = New(os.Stdout, cfg)
+	return nil
+}
 
-func newLogger() *slog.Logger {
-	opts := &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+// SetLevel parses level ("debug", "info", "warn", "error") and applies it.
+func SetLevel(level string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return fmt.Errorf("unsupported log level %q", level)
 	}
+	Level.Set(l)
+	return nil
+}
+
+type requestIDKey struct{}
+
+// WithRequestID stores the request id logged with every record made with ctx.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
 
-	handler := slog.NewTextHandler(os.Stdout, opts)
-	return slog.New(handler)
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
 }
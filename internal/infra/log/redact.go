@@ -0,0 +1,60 @@
+package log
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// E.164 numbers as stored, and national numbers with a trunk 0 as
+	// providers sometimes echo them back.
+	phonePattern = regexp.MustCompile(`\+\d{7,15}\b|\b0\d{9,10}\b`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@([A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+)
+
+// Keys whose values are always personal data, whatever they look like.
+var (
+	recipientKeys = map[string]bool{"to": true, "to_phone": true, "phone": true, "recipient": true}
+	contentKeys   = map[string]bool{"content": true, "text": true, "message_content": true}
+)
+
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	switch {
+	case recipientKeys[key]:
+		return slog.String(a.Key, MaskRecipient(a.Value.String()))
+	case contentKeys[key]:
+		return slog.String(a.Key, fmt.Sprintf("[redacted %d chars]", utf8.RuneCountInString(a.Value.String())))
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return a
+}
+
+// Redact masks every phone number and email address in s.
+func Redact(s string) string {
+	s = phonePattern.ReplaceAllStringFunc(s, MaskRecipient)
+	return emailPattern.ReplaceAllStringFunc(s, MaskRecipient)
+}
+
+// MaskRecipient keeps just enough of a phone number or email address to
+// tell recipients apart while debugging: "+90*******67", "j***@example.com".
+func MaskRecipient(s string) string {
+	if at := strings.LastIndexByte(s, '@'); at > 0 {
+		return s[:1] + "***" + s[at:]
+	}
+	if len(s) <= 5 {
+		return strings.Repeat("*", len(s))
+	}
+	return s[:3] + strings.Repeat("*", len(s)-5) + s[len(s)-2:]
+}
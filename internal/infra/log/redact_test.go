@@ -0,0 +1,116 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "e164 number", in: "send to +905551234567 failed", want: "send to +90********67 failed"},
+		{name: "national number with trunk prefix", in: "provider rejected 05551234567", want: "provider rejected 055******67"},
+		{name: "email address", in: "smtp rcpt to jane.doe@example.com: 550", want: "smtp rcpt to j***@example.com: 550"},
+		{name: "number and email", in: "+14155550123 / bob@mail.example.org", want: "+14*******23 / b***@mail.example.org"},
+		{name: "several numbers", in: "+905551234567,+905559876543", want: "+90********67,+90********43"},
+		{name: "short digits left alone", in: "retry 3 of 5, status 503", want: "retry 3 of 5, status 503"},
+		{name: "long id without plus left alone", in: "msg 123456789012 queued", want: "msg 123456789012 queued"},
+		{name: "no personal data", in: "connection refused", want: "connection refused"},
+		{name: "empty", in: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.in); got != tt.want {
+				t.Errorf("Redact(%q) = %q; want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskRecipient(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "e164 number", in: "+905551234567", want: "+90********67"},
+		{name: "email", in: "jane@example.com", want: "j***@example.com"},
+		{name: "short value", in: "12345", want: "*****"},
+		{name: "empty", in: "", want: ""},
+		{name: "six chars", in: "123456", want: "123*56"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskRecipient(tt.in); got != tt.want {
+				t.Errorf("MaskRecipient(%q) = %q; want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggerRedactsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []any
+		secrets []string
+		want    []string
+	}{
+		{
+			name:    "e164 number in error",
+			args:    []any{"err", fmt.Errorf("sms send: %w", errors.New("invalid destination +905551234567"))},
+			secrets: []string{"+905551234567"},
+			want:    []string{"+90********67"},
+		},
+		{
+			name:    "national number in error",
+			args:    []any{"err", errors.New("provider: unknown subscriber 05551234567")},
+			secrets: []string{"05551234567"},
+			want:    []string{"055******67"},
+		},
+		{
+			name:    "email in error",
+			args:    []any{"err", errors.New("smtp rcpt to: 550 no such user jane@example.com")},
+			secrets: []string{"jane@example.com"},
+			want:    []string{"j***@example.com"},
+		},
+		{
+			name:    "recipient and content keys",
+			args:    []any{"to", "jane@example.com", "content", "your code is 1234"},
+			secrets: []string{"jane@example.com", "your code is 1234"},
+			want:    []string{"j***@example.com", "[redacted 17 chars]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, format := range []string{FormatText, FormatJSON} {
+				var buf bytes.Buffer
+				New(&buf, Config{Format: format, Redact: true}).Error("send failed", tt.args...)
+				out := buf.String()
+				for _, s := range tt.secrets {
+					if strings.Contains(out, s) {
+						t.Errorf("%s output leaks %q: %s", format, s, out)
+					}
+				}
+				for _, s := range tt.want {
+					if !strings.Contains(out, s) {
+						t.Errorf("%s output missing %q: %s", format, s, out)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLoggerWithoutRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, Config{}).Error("send failed", "err", errors.New("invalid destination +905551234567"))
+	if !strings.Contains(buf.String(), "+905551234567") {
+		t.Errorf("unredacted output lost the number: %s", buf.String())
+	}
+}
@@ -0,0 +1,159 @@
+// Package metrics holds the Prometheus collectors exposed on /metrics.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+)
+
+const namespace = "dispatcher"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	MessagesCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_created_total",
+		Help:      "Messages accepted by the API, by channel and initial status.",
+	}, []string{"channel", "status"})
+
+	MessagesSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_sent_total",
+		Help:      "Messages handed to a provider successfully.",
+	}, []string{"channel"})
+
+	// MessagesFailed only counts messages that ran out of retries; attempts
+	// that will be retried are counted in MessagesRetried.
+	MessagesFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_failed_total",
+		Help:      "Messages that failed permanently after exhausting their retries.",
+	}, []string{"channel"})
+
+	MessagesRetried = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_retried_total",
+		Help:      "Failed send attempts that put the message back in the queue.",
+	}, []string{"channel"})
+
+	MessagesSuppressed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_suppressed_total",
+		Help:      "Messages not sent because the recipient is suppressed, at create or send time.",
+	}, []string{"channel"})
+
+	ProviderLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Latency of outgoing provider webhook requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"host", "code"})
+
+	HTTPLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of API requests by route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// StatusCallbacks counts callback attempts by result: delivered, retried
+	// (will be attempted again) or failed (given up).
+	StatusCallbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "status_callbacks_total",
+		Help:      "Status callback attempts to message creators, by result.",
+	}, []string{"result"})
+
+	OutboxEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_events_total",
+		Help:      "Outbox events handed to the sink, by result (published or failed).",
+	}, []string{"result"})
+
+	SchedulerRunning = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "scheduler_running",
+		Help:      "1 while the scheduler is started on this replica.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		MessagesCreated,
+		MessagesSent,
+		MessagesFailed,
+		MessagesRetried,
+		MessagesSuppressed,
+		ProviderLatency,
+		HTTPLatency,
+		StatusCallbacks,
+		OutboxEvents,
+		SchedulerRunning,
+	)
+}
+
+// StatusCounter reports how many messages are in each of statuses.
+type StatusCounter interface {
+	CountByStatus(ctx context.Context, statuses ...string) (map[string]int, error)
+}
+
+// queueStatuses are the statuses counted on every scrape: the ones messages
+// only pass through, which stay small. Counting the terminal ones would scan
+// the whole history.
+var queueStatuses = []string{"queued", "processing"}
+
+// RegisterQueueDepth exposes dispatcher_queue_depth{status} for the queued
+// and processing messages, read from src on every scrape.
+func RegisterQueueDepth(src StatusCounter) {
+	registry.MustRegister(&queueDepth{
+		src: src,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "queue_depth"),
+			"Messages currently queued or being processed.",
+			[]string{"status"}, nil,
+		),
+	})
+}
+
+type queueDepth struct {
+	src  StatusCounter
+	desc *prometheus.Desc
+}
+
+func (q *queueDepth) Describe(ch chan<- *prometheus.Desc) { ch <- q.desc }
+
+func (q *queueDepth) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	counts, err := q.src.CountByStatus(ctx, queueStatuses...)
+	if err != nil {
+		log.Logger.Error("queue depth scrape failed", "err", err)
+		ch <- prometheus.NewInvalidMetric(q.desc, err)
+		return
+	}
+	// Keep the states that drain to zero from disappearing from the graphs.
+	for _, status := range queueStatuses {
+		if _, ok := counts[status]; !ok {
+			counts[status] = 0
+		}
+	}
+	for status, n := range counts {
+		ch <- prometheus.MustNewConstMetric(q.desc, prometheus.GaugeValue, float64(n), status)
+	}
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
@@ -0,0 +1,24 @@
+-- 1) Delivery receipt statuses reported back by the provider
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM pg_enum e JOIN pg_type t ON t.oid = e.enumtypid
+    WHERE t.typname = 'message_status' AND e.enumlabel = 'delivered'
+  ) THEN
+    ALTER TYPE message_status ADD VALUE 'delivered';
+  END IF;
+
+  IF NOT EXISTS (
+    SELECT 1 FROM pg_enum e JOIN pg_type t ON t.oid = e.enumtypid
+    WHERE t.typname = 'message_status' AND e.enumlabel = 'undelivered'
+  ) THEN
+    ALTER TYPE message_status ADD VALUE 'undelivered';
+  END IF;
+END$$;
+
+-- 2) Receipt columns
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMPTZ;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS delivery_error_code VARCHAR(64);
+
+-- 3) Indexes (receipts are keyed by the provider's id)
+CREATE INDEX IF NOT EXISTS idx_messages_provider_message_id ON messages (provider_message_id);
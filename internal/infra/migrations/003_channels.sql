@@ -0,0 +1,18 @@
+-- 1) Channel the message is dispatched through (sms, email, push)
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS channel VARCHAR(16) NOT NULL DEFAULT 'sms';
+
+-- 2) Generic recipient address (phone number, email address or device token)
+DO $$
+BEGIN
+  IF EXISTS (
+    SELECT 1 FROM information_schema.columns
+    WHERE table_name = 'messages' AND column_name = 'to_phone'
+  ) THEN
+    ALTER TABLE messages RENAME COLUMN to_phone TO recipient;
+  END IF;
+END$$;
+
+ALTER TABLE messages ALTER COLUMN recipient TYPE VARCHAR(320);
+
+-- 3) Optional subject line (email)
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS subject VARCHAR(255);
@@ -0,0 +1,3 @@
+-- 1) SMS encoding and segment count, computed at enqueue time (NULL for non-sms channels)
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS encoding VARCHAR(8);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS segments INT;
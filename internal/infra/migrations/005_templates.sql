@@ -0,0 +1,23 @@
+-- 1) Named templates; every update adds a new version
+CREATE TABLE IF NOT EXISTS templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(128) NOT NULL UNIQUE,
+    default_locale VARCHAR(16) NOT NULL,
+    latest_version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 2) One body per (version, locale)
+CREATE TABLE IF NOT EXISTS template_versions (
+    template_id UUID NOT NULL REFERENCES templates (id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    locale VARCHAR(16) NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (template_id, version, locale)
+);
+
+-- 3) Template a message was rendered from (kept even if the template is deleted)
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS template_id UUID;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS template_version INT;
@@ -0,0 +1,19 @@
+-- 1) Status for messages that were not sent because the recipient is suppressed
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM pg_enum e JOIN pg_type t ON t.oid = e.enumtypid
+    WHERE t.typname = 'message_status' AND e.enumlabel = 'suppressed'
+  ) THEN
+    ALTER TYPE message_status ADD VALUE 'suppressed';
+  END IF;
+END$$;
+
+-- 2) Opt-out / compliance suppression list
+CREATE TABLE IF NOT EXISTS suppressions (
+    channel VARCHAR(16) NOT NULL,
+    recipient VARCHAR(320) NOT NULL,
+    reason VARCHAR(64) NOT NULL DEFAULT 'opt_out',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (channel, recipient)
+);
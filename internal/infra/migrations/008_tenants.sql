@@ -0,0 +1,19 @@
+-- 1) Tenants (product teams sharing the dispatcher); daily_quota NULL means unlimited
+CREATE TABLE IF NOT EXISTS tenants (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(128) NOT NULL,
+    daily_quota INT CHECK (daily_quota IS NULL OR daily_quota >= 0),
+    providers JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 2) Everything that existed before tenants belongs to the default tenant
+INSERT INTO tenants (id, name) VALUES ('default', 'Default') ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+
+-- 3) Tenant-scoped listing, daily quota counts and fair-share claiming
+CREATE INDEX IF NOT EXISTS idx_messages_tenant_created ON messages (tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_messages_queued_tenant ON messages (tenant_id, created_at) WHERE status = 'queued';
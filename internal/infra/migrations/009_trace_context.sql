@@ -0,0 +1,2 @@
+-- 1) W3C traceparent of the request that created the message, so the send can link back to it
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS traceparent VARCHAR(55);
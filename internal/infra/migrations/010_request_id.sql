@@ -0,0 +1,4 @@
+-- 1) X-Request-ID of the request that created the message, to find a request's messages from its logs
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS request_id VARCHAR(128);
+
+CREATE INDEX IF NOT EXISTS idx_messages_request_id ON messages (tenant_id, request_id) WHERE request_id IS NOT NULL;
@@ -0,0 +1,4 @@
+-- 1) Client-chosen label grouping messages (e.g. a campaign), to follow their progress together
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS batch_id VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_messages_batch ON messages (tenant_id, batch_id) WHERE batch_id IS NOT NULL;
@@ -0,0 +1,34 @@
+-- 1) Where to report a message's final status, set by its creator
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS callback_url VARCHAR(2048);
+
+-- 2) Outbound status callbacks, retried independently of the message send
+CREATE TABLE IF NOT EXISTS status_callbacks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_id UUID NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    tenant_id VARCHAR(64) NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_callbacks_due ON status_callbacks (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_status_callbacks_message ON status_callbacks (message_id);
+
+-- 3) Delivery log: one row per attempt
+CREATE TABLE IF NOT EXISTS status_callback_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    callback_id UUID NOT NULL REFERENCES status_callbacks (id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    status_code INT,
+    error TEXT,
+    duration_ms INT NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_callback_attempts_callback ON status_callback_attempts (callback_id, attempt);
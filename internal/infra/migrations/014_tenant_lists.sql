@@ -0,0 +1,11 @@
+-- 1) Templates and suppressions belong to a tenant; existing ones to the default tenant
+ALTER TABLE templates ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE suppressions ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+
+-- 2) Template names are unique per tenant
+ALTER TABLE templates DROP CONSTRAINT IF EXISTS templates_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_templates_tenant_name ON templates (tenant_id, name);
+
+-- 3) Each tenant keeps its own suppression list
+ALTER TABLE suppressions DROP CONSTRAINT IF EXISTS suppressions_pkey;
+ALTER TABLE suppressions ADD PRIMARY KEY (tenant_id, channel, recipient);
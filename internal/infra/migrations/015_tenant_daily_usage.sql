@@ -0,0 +1,8 @@
+-- 1) Messages created per tenant and UTC day; the quota check and the insert
+--    update it in one transaction
+CREATE TABLE IF NOT EXISTS tenant_daily_usage (
+    tenant_id VARCHAR(64) NOT NULL REFERENCES tenants (id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    used INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, day)
+);
@@ -0,0 +1,5 @@
+-- 1) Failed events wait out a backoff; claimed ones are hidden for a lease
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- 2) Oldest unpublished event per message
+CREATE INDEX IF NOT EXISTS idx_outbox_unpublished_message ON outbox (message_id, id) WHERE published_at IS NULL;
@@ -0,0 +1,16 @@
+-- 1) Default locale of each template version, repeated on its locale rows;
+--    older versions get the template's current default if they have a body
+--    for it, else their first locale
+ALTER TABLE template_versions ADD COLUMN IF NOT EXISTS default_locale VARCHAR(16);
+
+UPDATE template_versions v
+SET default_locale = COALESCE(
+    (SELECT t.default_locale
+     FROM templates t
+     JOIN template_versions d ON d.template_id = t.id AND d.version = v.version AND d.locale = t.default_locale
+     WHERE t.id = v.template_id),
+    (SELECT MIN(m.locale) FROM template_versions m WHERE m.template_id = v.template_id AND m.version = v.version)
+)
+WHERE v.default_locale IS NULL;
+
+ALTER TABLE template_versions ALTER COLUMN default_locale SET NOT NULL;
@@ -0,0 +1,3 @@
+-- 1) Each tenant signs its status callbacks with its own secrets, so no
+--    tenant can forge callbacks to another tenant's receiver
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS callback_signing_secrets TEXT[] NOT NULL DEFAULT '{}';
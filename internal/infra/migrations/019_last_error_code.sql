@@ -0,0 +1,3 @@
+-- 1) A stable code for the last send error, reported in message events and
+--    status callbacks instead of last_error, whose text may name internal hosts
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS last_error_code VARCHAR(64);
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+const apiKeyColumns = `id, tenant_id, name, prefix, scopes, created_at, last_used_at, revoked_at`
+
+type APIKeysRepo struct {
+	db *sql.DB
+}
+
+func NewAPIKeysRepo(db *sql.DB) *APIKeysRepo {
+	return &APIKeysRepo{db: db}
+}
+
+func scanAPIKey(row rowScanner) (domain.APIKey, error) {
+	var k domain.APIKey
+	err := row.Scan(
+		&k.ID,
+		&k.TenantID,
+		&k.Name,
+		&k.Prefix,
+		pq.Array(&k.Scopes),
+		&k.CreatedAt,
+		&k.LastUsedAt,
+		&k.RevokedAt,
+	)
+	return k, err
+}
+
+func (r *APIKeysRepo) CreateAPIKey(ctx context.Context, key domain.APIKey, hash string) (domain.APIKey, error) {
+	if key.TenantID == "" {
+		key.TenantID = domain.DefaultTenant
+	}
+	k, err := scanAPIKey(r.db.QueryRowContext(ctx, `
+		INSERT INTO api_keys (tenant_id, name, prefix, key_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+apiKeyColumns, key.TenantID, key.Name, key.Prefix, hash, pq.Array(key.Scopes)))
+	if pqCode(err) == pqForeignKeyViolation {
+		return domain.APIKey{}, domain.ErrTenantNotFound
+	}
+	return k, err
+}
+
+func (r *APIKeysRepo) FindAPIKeyByHash(ctx context.Context, hash string) (domain.APIKey, error) {
+	k, err := scanAPIKey(r.db.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL
+	`, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.APIKey{}, domain.ErrAPIKeyNotFound
+	}
+	return k, err
+}
+
+// TouchAPIKey records usage, at most once a minute per key to keep hot keys
+// from turning every request into a write.
+func (r *APIKeysRepo) TouchAPIKey(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys
+		SET last_used_at = NOW()
+		WHERE id = $1
+		  AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, id)
+	return err
+}
+
+func (r *APIKeysRepo) ListAPIKeys(ctx context.Context, tenantID string) ([]domain.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE $1::text = '' OR tenant_id = $1::text
+		ORDER BY created_at DESC
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+func (r *APIKeysRepo) RevokeAPIKey(ctx context.Context, tenantID, id string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys
+		SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+		  AND ($2::text = '' OR tenant_id = $2::text)
+	`, id, tenantID)
+	if err != nil {
+		if pqCode(err) == pqInvalidTextInput {
+			return domain.ErrAPIKeyNotFound
+		}
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+	return nil
+}
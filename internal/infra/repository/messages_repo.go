@@ -3,20 +3,176 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/XSAM/otelsql"
+	"github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
 	"github.com/temo927/go-msg-dispatcher/internal/domain"
 )
 
+const messageColumns = `id, tenant_id, channel, recipient, subject, content, encoding, segments, status, retry_count,
+	provider_message_id, last_error, last_error_code, created_at, updated_at, sent_at,
+	delivered_at, delivery_error_code, template_id, template_version, traceparent, request_id, batch_id, callback_url`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMessage(row rowScanner) (domain.Message, error) {
+	var m domain.Message
+	err := row.Scan(
+		&m.ID,
+		&m.TenantID,
+		&m.Channel,
+		&m.Recipient,
+		&m.Subject,
+		&m.Content,
+		&m.Encoding,
+		&m.Segments,
+		&m.Status,
+		&m.RetryCount,
+		&m.ProviderMessageID,
+		&m.LastError,
+		&m.LastErrorCode,
+		&m.CreatedAt,
+		&m.UpdatedAt,
+		&m.SentAt,
+		&m.DeliveredAt,
+		&m.DeliveryErrorCode,
+		&m.TemplateID,
+		&m.TemplateVersion,
+		&m.TraceParent,
+		&m.RequestID,
+		&m.BatchID,
+		&m.CallbackURL,
+	)
+	return m, err
+}
+
 type MessagesRepo struct {
-	db *sql.DB
+	db        *sql.DB
+	events    domain.EventPublisher
+	outbox    bool
+	callbacks bool
 }
 
 func NewMessagesRepo(db *sql.DB) *MessagesRepo {
 	return &MessagesRepo{db: db}
 }
 
+// UseEvents publishes created, claimed and delivery events to p once the
+// change is committed. The Sender publishes the outcome of each attempt.
+func (r *MessagesRepo) UseEvents(p domain.EventPublisher) {
+	r.events = p
+}
+
+// UseOutbox records every status change as an outbox event, in the same
+// transaction as the change, for an OutboxRelay to deliver downstream.
+func (r *MessagesRepo) UseOutbox() {
+	r.outbox = true
+}
+
+// UseStatusCallbacks queues a status callback for messages with a callback
+// URL once they are sent, suppressed or fail for good, in the same
+// transaction as that change. StatusCallbacks delivers it.
+func (r *MessagesRepo) UseStatusCallbacks() {
+	r.callbacks = true
+}
+
+func (r *MessagesRepo) writeOutbox(ctx context.Context, tx *sql.Tx, typ string, msgs ...domain.Message) error {
+	if !r.outbox {
+		return nil
+	}
+	for _, m := range msgs {
+		payload, err := json.Marshal(domain.NewMessageEvent(typ, m))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO outbox (message_id, tenant_id, event_type, payload)
+			VALUES ($1, $2, $3, $4)
+		`, m.ID, m.TenantID, typ, payload); err != nil {
+			return fmt.Errorf("write outbox: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeCallback queues the status callback of m for the terminal events.
+func (r *MessagesRepo) writeCallback(ctx context.Context, tx *sql.Tx, typ string, m domain.Message) error {
+	if !r.callbacks || m.CallbackURL == nil {
+		return nil
+	}
+	switch typ {
+	case domain.EventSent, domain.EventSuppressed, domain.EventFailed:
+	default:
+		return nil
+	}
+	payload, err := json.Marshal(domain.NewMessageEvent(typ, m))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO status_callbacks (message_id, tenant_id, url, payload)
+		VALUES ($1, $2, $3, $4)
+	`, m.ID, m.TenantID, *m.CallbackURL, payload); err != nil {
+		return fmt.Errorf("queue status callback: %w", err)
+	}
+	return nil
+}
+
+// transition runs query, an UPDATE of one message without its RETURNING
+// clause, together with the outbox event eventType names for the result
+// and, for a terminal event, the status callback.
+func (r *MessagesRepo) transition(ctx context.Context, eventType func(domain.Message) string, query string, args ...any) (domain.Message, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Message{}, err
+	}
+	defer tx.Rollback()
+
+	m, err := scanMessage(tx.QueryRowContext(ctx, query+` RETURNING `+messageColumns, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Message{}, domain.ErrMessageNotFound
+	}
+	if err != nil {
+		return domain.Message{}, err
+	}
+	typ := eventType(m)
+	if err := r.writeOutbox(ctx, tx, typ, m); err != nil {
+		return domain.Message{}, err
+	}
+	if err := r.writeCallback(ctx, tx, typ, m); err != nil {
+		return domain.Message{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Message{}, err
+	}
+	return m, nil
+}
+
+func eventType(typ string) func(domain.Message) string {
+	return func(domain.Message) string { return typ }
+}
+
+func (r *MessagesRepo) publish(ctx context.Context, typ string, msgs ...domain.Message) {
+	if r.events == nil {
+		return
+	}
+	for _, m := range msgs {
+		r.events.Publish(ctx, domain.NewMessageEvent(typ, m))
+	}
+}
+
+// ClaimNextBatch claims up to limit queued messages, taking them round-robin
+// across tenants (oldest first within each) so one tenant's backlog can't
+// starve the others. Candidates locked by another replica are skipped, which
+// may leave the batch short; they are picked up on the next tick.
 func (r *MessagesRepo) ClaimNextBatch(ctx context.Context, limit int) ([]domain.Message, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -25,19 +181,24 @@ func (r *MessagesRepo) ClaimNextBatch(ctx context.Context, limit int) ([]domain.
 	defer tx.Rollback()
 
 	rows, err := tx.QueryContext(ctx, `
-		UPDATE messages
-		SET status = 'processing'::message_status, updated_at = NOW()
-		WHERE id IN (
-			SELECT id
+		WITH ranked AS (
+			SELECT id, created_at,
+			       ROW_NUMBER() OVER (PARTITION BY tenant_id ORDER BY created_at) AS turn
 			FROM messages
 			WHERE status = 'queued'::message_status
-			ORDER BY created_at
-			FOR UPDATE SKIP LOCKED
-			LIMIT $1
+		), picked AS (
+			SELECT m.id
+			FROM messages m
+			JOIN (
+				SELECT id FROM ranked WHERE turn <= $1 ORDER BY turn, created_at LIMIT $1
+			) c ON c.id = m.id
+			WHERE m.status = 'queued'::message_status
+			FOR UPDATE OF m SKIP LOCKED
 		)
-		RETURNING id, to_phone, content, status, retry_count,
-		          provider_message_id, last_error, created_at, updated_at, sent_at
-	`, limit)
+		UPDATE messages
+		SET status = 'processing'::message_status, updated_at = NOW()
+		WHERE id IN (SELECT id FROM picked)
+		RETURNING `+messageColumns, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -45,32 +206,28 @@ func (r *MessagesRepo) ClaimNextBatch(ctx context.Context, limit int) ([]domain.
 
 	var msgs []domain.Message
 	for rows.Next() {
-		var m domain.Message
-		if err := rows.Scan(
-			&m.ID,
-			&m.ToPhone,
-			&m.Content,
-			&m.Status,
-			&m.RetryCount,
-			&m.ProviderMessageID,
-			&m.LastError,
-			&m.CreatedAt,
-			&m.UpdatedAt,
-			&m.SentAt,
-		); err != nil {
+		m, err := scanMessage(rows)
+		if err != nil {
 			return nil, err
 		}
 		msgs = append(msgs, m)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.writeOutbox(ctx, tx, domain.EventClaimed, msgs...); err != nil {
+		return nil, err
+	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
+	r.publish(ctx, domain.EventClaimed, msgs...)
 	return msgs, nil
 }
 
 func (r *MessagesRepo) MarkSent(ctx context.Context, id, providerMessageID string) error {
-	_, err := r.db.ExecContext(ctx, `
+	_, err := r.transition(ctx, eventType(domain.EventSent), `
 		UPDATE messages
 		SET status = 'sent'::message_status,
 		    provider_message_id = $2,
@@ -82,7 +239,12 @@ func (r *MessagesRepo) MarkSent(ctx context.Context, id, providerMessageID strin
 }
 
 func (r *MessagesRepo) MarkFailed(ctx context.Context, id string, cause error, maxRetries int) error {
-	_, err := r.db.ExecContext(ctx, `
+	_, err := r.transition(ctx, func(m domain.Message) string {
+		if m.Status == "failed" {
+			return domain.EventFailed
+		}
+		return domain.EventRetried
+	}, `
 		UPDATE messages
 		SET status = CASE
 			             WHEN retry_count + 1 >= $2 THEN 'failed'::message_status
@@ -90,21 +252,128 @@ func (r *MessagesRepo) MarkFailed(ctx context.Context, id string, cause error, m
 		             END,
 		    retry_count = retry_count + 1,
 		    last_error = $3,
+		    last_error_code = $4,
+		    updated_at = NOW()
+		WHERE id = $1
+	`, id, maxRetries, cause.Error(), domain.SendErrorCode(cause))
+	return err
+}
+
+func (r *MessagesRepo) Requeue(ctx context.Context, id string, cause error) error {
+	_, err := r.transition(ctx, eventType(domain.EventRetried), `
+		UPDATE messages
+		SET status = 'queued'::message_status,
+		    last_error = $2,
+		    last_error_code = $3,
+		    updated_at = NOW()
+		WHERE id = $1
+	`, id, cause.Error(), domain.SendErrorCode(cause))
+	return err
+}
+
+func (r *MessagesRepo) MarkSuppressed(ctx context.Context, id string) error {
+	_, err := r.transition(ctx, eventType(domain.EventSuppressed), `
+		UPDATE messages
+		SET status = 'suppressed'::message_status,
 		    updated_at = NOW()
 		WHERE id = $1
-	`, id, maxRetries, cause.Error())
+	`, id)
 	return err
 }
 
-func (r *MessagesRepo) ListSent(ctx context.Context, limit, offset int) ([]domain.Message, error) {
+// ApplyDeliveryReport moves a sent message to delivered/undelivered. Receipts
+// may arrive more than once or out of order, so one older than the receipt
+// already applied leaves the message as it is. Provider message ids are
+// only unique per provider; a report matching several messages is refused
+// with ErrAmbiguousReport rather than applied to all of them.
+func (r *MessagesRepo) ApplyDeliveryReport(ctx context.Context, report domain.DeliveryReport) (domain.Message, error) {
+	status := "undelivered"
+	if report.Delivered {
+		status = "delivered"
+	}
+	var errorCode *string
+	if report.ErrorCode != "" {
+		errorCode = &report.ErrorCode
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Message{}, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages
+		WHERE provider_message_id = $1
+		  AND ($2::text = '' OR channel = $2::text)
+		  AND status IN ('sent'::message_status, 'delivered'::message_status, 'undelivered'::message_status)
+		LIMIT 2
+		FOR UPDATE
+	`, report.ProviderMessageID, report.Channel)
+	if err != nil {
+		return domain.Message{}, err
+	}
+	var matches []domain.Message
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			rows.Close()
+			return domain.Message{}, err
+		}
+		matches = append(matches, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return domain.Message{}, err
+	}
+	switch {
+	case len(matches) == 0:
+		return domain.Message{}, domain.ErrMessageNotFound
+	case len(matches) > 1:
+		return domain.Message{}, domain.ErrAmbiguousReport
+	}
+	current := matches[0]
+	// Providers report whole seconds.
+	if current.SentAt != nil && report.At.Before(current.SentAt.Truncate(time.Second)) {
+		return domain.Message{}, domain.ErrReportBeforeSend
+	}
+	if current.DeliveredAt != nil && report.At.Before(*current.DeliveredAt) {
+		return current, nil
+	}
+
+	m, err := scanMessage(tx.QueryRowContext(ctx, `
+		UPDATE messages
+		SET status = $2::message_status,
+		    delivered_at = $3,
+		    delivery_error_code = $4,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+messageColumns,
+		current.ID, status, report.At, errorCode))
+	if err != nil {
+		return domain.Message{}, err
+	}
+	// The delivery event types are named after the statuses.
+	if err := r.writeOutbox(ctx, tx, status, m); err != nil {
+		return domain.Message{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Message{}, err
+	}
+	r.publish(ctx, status, m)
+	return m, nil
+}
+
+func (r *MessagesRepo) ListSent(ctx context.Context, tenantID string, limit, offset int) ([]domain.Message, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, to_phone, content, status, retry_count,
-		       provider_message_id, last_error, created_at, updated_at, sent_at
+		SELECT `+messageColumns+`
 		FROM messages
-		WHERE status = 'sent'::message_status
+		WHERE status IN ('sent'::message_status, 'delivered'::message_status, 'undelivered'::message_status)
+		  AND ($3::text = '' OR tenant_id = $3::text)
 		ORDER BY sent_at DESC
 		LIMIT $1 OFFSET $2
-	`, limit, offset)
+	`, limit, offset, tenantID)
 	if err != nil {
 		return nil, err
 	}
@@ -112,19 +381,8 @@ func (r *MessagesRepo) ListSent(ctx context.Context, limit, offset int) ([]domai
 
 	var msgs []domain.Message
 	for rows.Next() {
-		var m domain.Message
-		if err := rows.Scan(
-			&m.ID,
-			&m.ToPhone,
-			&m.Content,
-			&m.Status,
-			&m.RetryCount,
-			&m.ProviderMessageID,
-			&m.LastError,
-			&m.CreatedAt,
-			&m.UpdatedAt,
-			&m.SentAt,
-		); err != nil {
+		m, err := scanMessage(rows)
+		if err != nil {
 			return nil, err
 		}
 		msgs = append(msgs, m)
@@ -132,33 +390,159 @@ func (r *MessagesRepo) ListSent(ctx context.Context, limit, offset int) ([]domai
 	return msgs, nil
 }
 
-func (r *MessagesRepo) Create(ctx context.Context, to, content string) (domain.Message, error) {
-	var m domain.Message
-	err := r.db.QueryRowContext(ctx, `
-		INSERT INTO messages (to_phone, content)
-		VALUES ($1, $2)
-		RETURNING id, to_phone, content, status, retry_count,
-		          provider_message_id, last_error, created_at, updated_at, sent_at
-	`, to, content).Scan(
-		&m.ID,
-		&m.ToPhone,
-		&m.Content,
-		&m.Status,
-		&m.RetryCount,
-		&m.ProviderMessageID,
-		&m.LastError,
-		&m.CreatedAt,
-		&m.UpdatedAt,
-		&m.SentAt,
-	)
+func (r *MessagesRepo) GetMessage(ctx context.Context, tenantID, id string) (domain.Message, error) {
+	m, err := scanMessage(r.db.QueryRowContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages
+		WHERE id = $1
+		  AND ($2::text = '' OR tenant_id = $2::text)
+	`, id, tenantID))
+	if errors.Is(err, sql.ErrNoRows) || pqCode(err) == pqInvalidTextInput {
+		return domain.Message{}, domain.ErrMessageNotFound
+	}
+	return m, err
+}
+
+func (r *MessagesRepo) ListByRequestID(ctx context.Context, tenantID, requestID string) ([]domain.Message, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+messageColumns+`
+		FROM messages
+		WHERE request_id = $1
+		  AND ($2::text = '' OR tenant_id = $2::text)
+		ORDER BY created_at
+	`, requestID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []domain.Message
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+func (r *MessagesRepo) Create(ctx context.Context, msg domain.Message) (domain.Message, error) {
+	return r.create(ctx, msg, nil)
+}
+
+// CreateWithinQuota counts the message in the tenant's usage row for the day
+// in the same transaction as the insert. The row lock serialises concurrent
+// creates of one tenant, so the quota can't be overshot by racing requests.
+func (r *MessagesRepo) CreateWithinQuota(ctx context.Context, msg domain.Message, quota int) (domain.Message, error) {
+	return r.create(ctx, msg, &quota)
+}
+
+func (r *MessagesRepo) create(ctx context.Context, msg domain.Message, quota *int) (domain.Message, error) {
+	if msg.Channel == "" {
+		msg.Channel = domain.ChannelSMS
+	}
+	if msg.Status == "" {
+		msg.Status = "queued"
+	}
+	if msg.TenantID == "" {
+		msg.TenantID = domain.DefaultTenant
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Message{}, err
+	}
+	defer tx.Rollback()
+
+	if quota != nil {
+		if err := useQuota(ctx, tx, msg.TenantID, *quota); err != nil {
+			return domain.Message{}, err
+		}
+	}
+
+	m, err := scanMessage(tx.QueryRowContext(ctx, `
+		INSERT INTO messages (channel, recipient, subject, content, encoding, segments,
+		                      template_id, template_version, status, tenant_id, traceparent, request_id, batch_id, callback_url)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::message_status, $10, $11, $12, $13, $14)
+		RETURNING `+messageColumns,
+		msg.Channel, msg.Recipient, msg.Subject, msg.Content, msg.Encoding, msg.Segments,
+		msg.TemplateID, msg.TemplateVersion, msg.Status, msg.TenantID, msg.TraceParent, msg.RequestID, msg.BatchID, msg.CallbackURL))
 	if err != nil {
 		return domain.Message{}, err
 	}
+	if err := r.writeOutbox(ctx, tx, domain.EventCreated, m); err != nil {
+		return domain.Message{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Message{}, err
+	}
+	r.publish(ctx, domain.EventCreated, m)
 	return m, nil
 }
 
+// useQuota takes one message off tenantID's quota for the current UTC day.
+// The day's row is seeded once from the messages already created that day,
+// so usage from before the row existed still counts; after that each message
+// is a single conditional increment.
+func useQuota(ctx context.Context, tx *sql.Tx, tenantID string, quota int) error {
+	dayStart := time.Now().UTC().Truncate(24 * time.Hour)
+	day := dayStart.Format("2006-01-02")
+	// The count is a scalar subquery so it only runs when the row is missing.
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO tenant_daily_usage (tenant_id, day, used)
+		SELECT $1, $2::date, (SELECT COUNT(*) FROM messages WHERE tenant_id = $1 AND created_at >= $3)
+		WHERE NOT EXISTS (SELECT 1 FROM tenant_daily_usage WHERE tenant_id = $1 AND day = $2::date)
+		ON CONFLICT (tenant_id, day) DO NOTHING
+	`, tenantID, day, dayStart); err != nil {
+		return err
+	}
+
+	var used int
+	err := tx.QueryRowContext(ctx, `
+		UPDATE tenant_daily_usage
+		SET used = used + 1
+		WHERE tenant_id = $1 AND day = $2::date AND used < $3
+		RETURNING used
+	`, tenantID, day, quota).Scan(&used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrQuotaExceeded
+	}
+	return err
+}
+
+// CountByStatus counts the messages in each of statuses. It reads
+// idx_messages_status_created, so it stays cheap for the small, active
+// statuses however many messages have been sent.
+func (r *MessagesRepo) CountByStatus(ctx context.Context, statuses ...string) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT status, COUNT(*)
+		FROM messages
+		WHERE status = ANY($1::message_status[])
+		GROUP BY status
+	`, pq.Array(statuses))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var (
+			status string
+			n      int
+		)
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+// Connect opens the pool through otelsql, so every query made with a context
+// shows up as a span of the caller's trace.
 func Connect(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("postgres", dsn)
+	db, err := otelsql.Open("postgres", dsn, otelsql.WithAttributes(semconv.DBSystemPostgreSQL))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+type OutboxRepo struct {
+	db *sql.DB
+}
+
+func NewOutboxRepo(db *sql.DB) *OutboxRepo {
+	return &OutboxRepo{db: db}
+}
+
+// ClaimOutbox only looks at the oldest unpublished event of each message, so
+// a later event never overtakes an earlier one, and skips heads that are not
+// due, so a failing message waits out its backoff without holding up the
+// others. Claimed events get next_attempt_at pushed past the lease instead of
+// staying locked while they are published; the re-check of next_attempt_at
+// in the outer WHERE keeps two replicas from claiming the same event.
+func (r *OutboxRepo) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]domain.OutboxEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE outbox
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT DISTINCT ON (message_id) id, next_attempt_at
+				FROM outbox
+				WHERE published_at IS NULL
+				ORDER BY message_id, id
+			) head
+			WHERE next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $1
+		)
+		  AND published_at IS NULL
+		  AND next_attempt_at <= NOW()
+		RETURNING id, message_id, tenant_id, event_type, payload, created_at, attempts
+	`, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []domain.OutboxEvent
+	for rows.Next() {
+		var ev domain.OutboxEvent
+		if err := rows.Scan(&ev.ID, &ev.MessageID, &ev.TenantID, &ev.Type, &ev.Payload, &ev.CreatedAt, &ev.Attempts); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+func (r *OutboxRepo) MarkOutboxPublished(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox SET published_at = NOW() WHERE id = ANY($1)
+	`, pq.Array(ids))
+	return err
+}
+
+func (r *OutboxRepo) RecordOutboxFailure(ctx context.Context, id int64, nextAttemptAt time.Time, cause error) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox
+		SET attempts = attempts + 1,
+		    last_error = $2,
+		    next_attempt_at = $3
+		WHERE id = $1
+	`, id, cause.Error(), nextAttemptAt)
+	return err
+}
+
+func (r *OutboxRepo) PruneOutbox(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM outbox WHERE published_at IS NOT NULL AND published_at < $1
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
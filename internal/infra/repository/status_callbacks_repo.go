@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+const callbackColumns = `id, message_id, tenant_id, url, payload, status, attempts,
+	next_attempt_at, last_error, created_at, delivered_at`
+
+func scanCallback(row rowScanner) (domain.StatusCallback, error) {
+	var cb domain.StatusCallback
+	err := row.Scan(
+		&cb.ID,
+		&cb.MessageID,
+		&cb.TenantID,
+		&cb.URL,
+		&cb.Payload,
+		&cb.Status,
+		&cb.Attempts,
+		&cb.NextAttemptAt,
+		&cb.LastError,
+		&cb.CreatedAt,
+		&cb.DeliveredAt,
+	)
+	return cb, err
+}
+
+type StatusCallbacksRepo struct {
+	db *sql.DB
+}
+
+func NewStatusCallbacksRepo(db *sql.DB) *StatusCallbacksRepo {
+	return &StatusCallbacksRepo{db: db}
+}
+
+// ClaimDueCallbacks pushes next_attempt_at of the claimed rows past the lease
+// instead of holding row locks during the POST. A replica that dies mid-way
+// leaves them to be retried once the lease runs out.
+func (r *StatusCallbacksRepo) ClaimDueCallbacks(ctx context.Context, limit int, lease time.Duration) ([]domain.StatusCallback, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE status_callbacks
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond',
+		    updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM status_callbacks
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+callbackColumns,
+		limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.StatusCallback
+	for rows.Next() {
+		cb, err := scanCallback(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, cb)
+	}
+	return out, rows.Err()
+}
+
+func (r *StatusCallbacksRepo) RecordCallbackAttempt(ctx context.Context, cb domain.StatusCallback, a domain.CallbackAttempt) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO status_callback_attempts (callback_id, attempt, status_code, error, duration_ms, attempted_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, cb.ID, a.Attempt, a.StatusCode, a.Error, a.Duration.Milliseconds(), a.AttemptedAt); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE status_callbacks
+		SET status = $2,
+		    attempts = $3,
+		    next_attempt_at = $4,
+		    last_error = $5,
+		    delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() ELSE delivered_at END,
+		    updated_at = NOW()
+		WHERE id = $1
+	`, cb.ID, cb.Status, cb.Attempts, cb.NextAttemptAt, cb.LastError); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *StatusCallbacksRepo) ListCallbacks(ctx context.Context, tenantID, messageID string) ([]domain.StatusCallback, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+callbackColumns+`
+		FROM status_callbacks
+		WHERE message_id = $1
+		  AND ($2::text = '' OR tenant_id = $2::text)
+		ORDER BY created_at
+	`, messageID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.StatusCallback
+	index := map[string]int{}
+	for rows.Next() {
+		cb, err := scanCallback(rows)
+		if err != nil {
+			return nil, err
+		}
+		index[cb.ID] = len(out)
+		out = append(out, cb)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	rows, err = r.db.QueryContext(ctx, `
+		SELECT a.callback_id, a.attempt, a.status_code, a.error, a.duration_ms, a.attempted_at
+		FROM status_callback_attempts a
+		JOIN status_callbacks c ON c.id = a.callback_id
+		WHERE c.message_id = $1
+		ORDER BY a.callback_id, a.attempt
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			callbackID string
+			a          domain.CallbackAttempt
+			ms         int64
+		)
+		if err := rows.Scan(&callbackID, &a.Attempt, &a.StatusCode, &a.Error, &ms, &a.AttemptedAt); err != nil {
+			return nil, err
+		}
+		a.Duration = time.Duration(ms) * time.Millisecond
+		if i, ok := index[callbackID]; ok {
+			out[i].Log = append(out[i].Log, a)
+		}
+	}
+	return out, rows.Err()
+}
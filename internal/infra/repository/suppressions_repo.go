@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+type SuppressionsRepo struct {
+	db *sql.DB
+}
+
+func NewSuppressionsRepo(db *sql.DB) *SuppressionsRepo {
+	return &SuppressionsRepo{db: db}
+}
+
+func (r *SuppressionsRepo) IsSuppressed(ctx context.Context, tenantID, channel, recipient string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM suppressions WHERE tenant_id = $1 AND channel = $2 AND recipient = $3)
+	`, tenantID, channel, recipient).Scan(&exists)
+	return exists, err
+}
+
+// AddSuppression is idempotent: suppressing an already suppressed recipient
+// only updates the reason.
+func (r *SuppressionsRepo) AddSuppression(ctx context.Context, s domain.Suppression) (domain.Suppression, error) {
+	out := s
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO suppressions (tenant_id, channel, recipient, reason)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id, channel, recipient) DO UPDATE SET reason = EXCLUDED.reason
+		RETURNING created_at
+	`, s.TenantID, s.Channel, s.Recipient, s.Reason).Scan(&out.CreatedAt)
+	if err != nil {
+		if pqCode(err) == pqForeignKeyViolation {
+			return domain.Suppression{}, domain.ErrTenantNotFound
+		}
+		return domain.Suppression{}, err
+	}
+	return out, nil
+}
+
+func (r *SuppressionsRepo) RemoveSuppression(ctx context.Context, tenantID, channel, recipient string) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM suppressions WHERE tenant_id = $1 AND channel = $2 AND recipient = $3
+	`, tenantID, channel, recipient)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.ErrSuppressionNotFound
+	}
+	return nil
+}
+
+func (r *SuppressionsRepo) ListSuppressions(ctx context.Context, tenantID string, limit, offset int) ([]domain.Suppression, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT tenant_id, channel, recipient, reason, created_at
+		FROM suppressions
+		WHERE ($3::text = '' OR tenant_id = $3::text)
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2
+	`, limit, offset, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.Suppression
+	for rows.Next() {
+		var s domain.Suppression
+		if err := rows.Scan(&s.TenantID, &s.Channel, &s.Recipient, &s.Reason, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+	pqInvalidTextInput    = "22P02"
+)
+
+type TemplatesRepo struct {
+	db *sql.DB
+}
+
+func NewTemplatesRepo(db *sql.DB) *TemplatesRepo {
+	return &TemplatesRepo{db: db}
+}
+
+func (r *TemplatesRepo) CreateTemplate(ctx context.Context, t domain.Template) (domain.Template, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Template{}, err
+	}
+	defer tx.Rollback()
+
+	out := domain.Template{TenantID: t.TenantID, Name: t.Name, DefaultLocale: t.DefaultLocale, Version: 1, Bodies: t.Bodies}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO templates (tenant_id, name, default_locale)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at
+	`, t.TenantID, t.Name, t.DefaultLocale).Scan(&out.ID, &out.CreatedAt, &out.UpdatedAt)
+	if err != nil {
+		switch pqCode(err) {
+		case pqUniqueViolation:
+			return domain.Template{}, domain.ErrTemplateExists
+		case pqForeignKeyViolation:
+			return domain.Template{}, domain.ErrTenantNotFound
+		}
+		return domain.Template{}, err
+	}
+
+	if err := insertBodies(ctx, tx, out.ID, out.Version, out.DefaultLocale, t.Bodies); err != nil {
+		return domain.Template{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Template{}, err
+	}
+	return out, nil
+}
+
+func (r *TemplatesRepo) AddTemplateVersion(ctx context.Context, tenantID, id, defaultLocale string, bodies map[string]string) (domain.Template, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Template{}, err
+	}
+	defer tx.Rollback()
+
+	out := domain.Template{Bodies: bodies}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE templates
+		SET latest_version = latest_version + 1,
+		    default_locale = COALESCE(NULLIF($2, ''), default_locale),
+		    updated_at = NOW()
+		WHERE id = $1
+		  AND ($3::text = '' OR tenant_id = $3::text)
+		RETURNING id, tenant_id, name, default_locale, latest_version, created_at, updated_at
+	`, id, defaultLocale, tenantID).Scan(&out.ID, &out.TenantID, &out.Name, &out.DefaultLocale, &out.Version, &out.CreatedAt, &out.UpdatedAt)
+	if err != nil {
+		return domain.Template{}, templateErr(err)
+	}
+
+	if err := insertBodies(ctx, tx, out.ID, out.Version, out.DefaultLocale, bodies); err != nil {
+		return domain.Template{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Template{}, err
+	}
+	return out, nil
+}
+
+func (r *TemplatesRepo) GetTemplate(ctx context.Context, tenantID, id string, version int) (domain.Template, error) {
+	var t domain.Template
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, tenant_id, name, default_locale, latest_version, created_at, updated_at
+		FROM templates
+		WHERE id = $1
+		  AND ($2::text = '' OR tenant_id = $2::text)
+	`, id, tenantID).Scan(&t.ID, &t.TenantID, &t.Name, &t.DefaultLocale, &t.Version, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return domain.Template{}, templateErr(err)
+	}
+	if version > 0 {
+		t.Version = version
+	}
+
+	// Every version keeps the default locale it was stored with; the
+	// template's own is that of the latest version.
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT locale, body, default_locale
+		FROM template_versions
+		WHERE template_id = $1 AND version = $2
+	`, t.ID, t.Version)
+	if err != nil {
+		return domain.Template{}, err
+	}
+	defer rows.Close()
+
+	t.Bodies = map[string]string{}
+	for rows.Next() {
+		var locale, body string
+		if err := rows.Scan(&locale, &body, &t.DefaultLocale); err != nil {
+			return domain.Template{}, err
+		}
+		t.Bodies[locale] = body
+	}
+	if err := rows.Err(); err != nil {
+		return domain.Template{}, err
+	}
+	if len(t.Bodies) == 0 {
+		return domain.Template{}, domain.ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+// ListTemplates returns the latest version of each template.
+func (r *TemplatesRepo) ListTemplates(ctx context.Context, tenantID string, limit, offset int) ([]domain.Template, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.id, t.tenant_id, t.name, t.default_locale, t.latest_version, t.created_at, t.updated_at,
+		       v.locale, v.body
+		FROM (
+			SELECT * FROM templates
+			WHERE ($3::text = '' OR tenant_id = $3::text)
+			ORDER BY name, tenant_id
+			LIMIT $1 OFFSET $2
+		) t
+		JOIN template_versions v ON v.template_id = t.id AND v.version = t.latest_version
+		ORDER BY t.name, t.tenant_id, v.locale
+	`, limit, offset, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.Template
+	for rows.Next() {
+		var t domain.Template
+		var locale, body string
+		if err := rows.Scan(&t.ID, &t.TenantID, &t.Name, &t.DefaultLocale, &t.Version, &t.CreatedAt, &t.UpdatedAt, &locale, &body); err != nil {
+			return nil, err
+		}
+		if n := len(out); n > 0 && out[n-1].ID == t.ID {
+			out[n-1].Bodies[locale] = body
+			continue
+		}
+		t.Bodies = map[string]string{locale: body}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (r *TemplatesRepo) DeleteTemplate(ctx context.Context, tenantID, id string) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM templates WHERE id = $1 AND ($2::text = '' OR tenant_id = $2::text)
+	`, id, tenantID)
+	if err != nil {
+		return templateErr(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.ErrTemplateNotFound
+	}
+	return nil
+}
+
+func insertBodies(ctx context.Context, tx *sql.Tx, id string, version int, defaultLocale string, bodies map[string]string) error {
+	for locale, body := range bodies {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO template_versions (template_id, version, locale, body, default_locale)
+			VALUES ($1, $2, $3, $4, $5)
+		`, id, version, locale, body, defaultLocale); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateErr maps "no row" and malformed UUIDs to ErrTemplateNotFound.
+func templateErr(err error) error {
+	if errors.Is(err, sql.ErrNoRows) || pqCode(err) == pqInvalidTextInput {
+		return domain.ErrTemplateNotFound
+	}
+	return err
+}
+
+func pqCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+const tenantColumns = `id, name, daily_quota, providers, callback_signing_secrets, created_at, updated_at`
+
+type TenantsRepo struct {
+	db *sql.DB
+}
+
+func NewTenantsRepo(db *sql.DB) *TenantsRepo {
+	return &TenantsRepo{db: db}
+}
+
+func scanTenant(row rowScanner) (domain.Tenant, error) {
+	var (
+		t         domain.Tenant
+		providers []byte
+	)
+	if err := row.Scan(&t.ID, &t.Name, &t.DailyQuota, &providers, pq.Array(&t.CallbackSigningSecrets), &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return domain.Tenant{}, err
+	}
+	if err := json.Unmarshal(providers, &t.Providers); err != nil {
+		return domain.Tenant{}, err
+	}
+	return t, nil
+}
+
+func (r *TenantsRepo) CreateTenant(ctx context.Context, t domain.Tenant) (domain.Tenant, error) {
+	providers, err := marshalProviders(t.Providers)
+	if err != nil {
+		return domain.Tenant{}, err
+	}
+	out, err := scanTenant(r.db.QueryRowContext(ctx, `
+		INSERT INTO tenants (id, name, daily_quota, providers, callback_signing_secrets)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+tenantColumns, t.ID, t.Name, t.DailyQuota, providers, pq.Array(secretsOrEmpty(t.CallbackSigningSecrets))))
+	if pqCode(err) == pqUniqueViolation {
+		return domain.Tenant{}, domain.ErrTenantExists
+	}
+	return out, err
+}
+
+func (r *TenantsRepo) GetTenant(ctx context.Context, id string) (domain.Tenant, error) {
+	t, err := scanTenant(r.db.QueryRowContext(ctx, `
+		SELECT `+tenantColumns+` FROM tenants WHERE id = $1
+	`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Tenant{}, domain.ErrTenantNotFound
+	}
+	return t, err
+}
+
+func (r *TenantsRepo) ListTenants(ctx context.Context, limit, offset int) ([]domain.Tenant, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+tenantColumns+`
+		FROM tenants
+		ORDER BY id
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.Tenant
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (r *TenantsRepo) UpdateTenant(ctx context.Context, t domain.Tenant) (domain.Tenant, error) {
+	providers, err := marshalProviders(t.Providers)
+	if err != nil {
+		return domain.Tenant{}, err
+	}
+	out, err := scanTenant(r.db.QueryRowContext(ctx, `
+		UPDATE tenants
+		SET name = $2, daily_quota = $3, providers = $4, callback_signing_secrets = $5, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+tenantColumns, t.ID, t.Name, t.DailyQuota, providers, pq.Array(secretsOrEmpty(t.CallbackSigningSecrets))))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Tenant{}, domain.ErrTenantNotFound
+	}
+	return out, err
+}
+
+func marshalProviders(p map[string]domain.ProviderConfig) ([]byte, error) {
+	if p == nil {
+		p = map[string]domain.ProviderConfig{}
+	}
+	return json.Marshal(p)
+}
+
+// secretsOrEmpty stores a missing list as '{}', since the column is NOT NULL.
+func secretsOrEmpty(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,162 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+)
+
+type Config struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration
+	// DisableStartTLS skips STARTTLS even if the server offers it, for
+	// local SMTP stand-ins with self-signed certificates.
+	DisableStartTLS bool
+}
+
+// Client delivers email-channel messages over SMTP. The returned provider
+// message id is the generated Message-ID header.
+type Client struct {
+	cfg    Config
+	host   string
+	domain string
+}
+
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("smtp: invalid addr %q: %w", cfg.Addr, err)
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("smtp: invalid from address: %w", err)
+	}
+	domainPart := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	return &Client{cfg: cfg, host: host, domain: domainPart}, nil
+}
+
+func (c *Client) Send(ctx context.Context, msg domain.Message) (string, error) {
+	from, _ := mail.ParseAddress(c.cfg.From)
+	to, err := mail.ParseAddress(msg.Recipient)
+	if err != nil {
+		return "", fmt.Errorf("invalid recipient: %w", err)
+	}
+
+	messageID, err := c.newMessageID()
+	if err != nil {
+		return "", err
+	}
+	body, err := buildMessage(from, to, msg, messageID)
+	if err != nil {
+		return "", fmt.Errorf("build message: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.cfg.Addr)
+	if err != nil {
+		return "", fmt.Errorf("smtp dial: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		conn.Close()
+		return "", fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer client.Close()
+
+	if err := client.Hello("localhost"); err != nil {
+		return "", fmt.Errorf("smtp hello: %w", err)
+	}
+	if ok, _ := client.Extension("STARTTLS"); ok && !c.cfg.DisableStartTLS {
+		if err := client.StartTLS(&tls.Config{ServerName: c.host, MinVersion: tls.VersionTLS12}); err != nil {
+			return "", fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if c.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.cfg.Username, c.cfg.Password, c.host)); err != nil {
+			return "", fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return "", fmt.Errorf("smtp mail from: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return "", fmt.Errorf("smtp rcpt to: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return "", fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return "", fmt.Errorf("smtp write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("smtp data close: %w", err)
+	}
+	_ = client.Quit()
+
+	log.Logger.Info("smtp accepted", "msg_id", msg.ID, "provider_message_id", messageID)
+	return messageID, nil
+}
+
+func (c *Client) newMessageID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate message id: %w", err)
+	}
+	return hex.EncodeToString(b) + "@" + c.domain, nil
+}
+
+func buildMessage(from, to *mail.Address, msg domain.Message, messageID string) ([]byte, error) {
+	subject := ""
+	if msg.Subject != nil {
+		subject = *msg.Subject
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Message-ID: <%s>\r\n", messageID)
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(msg.Content)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
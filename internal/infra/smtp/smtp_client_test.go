@@ -0,0 +1,194 @@
+package smtp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+// fakeServer is a minimal in-process SMTP server that accepts one message
+// per connection and records what it was sent.
+type fakeServer struct {
+	ln         net.Listener
+	rejectRcpt bool
+
+	mu    sync.Mutex
+	conns int
+	from  string
+	rcpt  string
+	data  string
+}
+
+func newFakeServer(t *testing.T, rejectRcpt bool) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeServer{ln: ln, rejectRcpt: rejectRcpt}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) addr() string { return s.ln.Addr().String() }
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 fake")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.mu.Lock()
+			s.from = line[len("MAIL FROM:"):]
+			s.mu.Unlock()
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			if s.rejectRcpt {
+				reply("550 no such user")
+				continue
+			}
+			s.mu.Lock()
+			s.rcpt = line[len("RCPT TO:"):]
+			s.mu.Unlock()
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *fakeServer) snapshot() (conns int, from, rcpt, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns, s.from, s.rcpt, s.data
+}
+
+func TestClientSend(t *testing.T) {
+	subject := "Sipariş onayı"
+	msg := domain.Message{
+		ID:        "msg-1",
+		Recipient: "Jane Doe <jane@example.org>",
+		Subject:   &subject,
+		Content:   "Merhaba Jane, siparişiniz yolda.",
+	}
+
+	tests := []struct {
+		name       string
+		rejectRcpt bool
+		msg        domain.Message
+		wantErr    string
+		wantConns  int
+	}{
+		{name: "accepted", msg: msg, wantConns: 1},
+		{name: "recipient rejected", rejectRcpt: true, msg: msg, wantErr: "smtp rcpt to", wantConns: 1},
+		{name: "invalid recipient is not dialed", msg: domain.Message{ID: "msg-2", Recipient: "not an address"}, wantErr: "invalid recipient", wantConns: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeServer(t, tt.rejectRcpt)
+			c, err := NewClient(Config{Addr: srv.addr(), From: "Dispatcher <noreply@example.com>", Timeout: 5 * time.Second})
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+
+			id, err := c.Send(context.Background(), tt.msg)
+			conns, from, rcpt, data := srv.snapshot()
+			if conns != tt.wantConns {
+				t.Errorf("connections = %d; want %d", conns, tt.wantConns)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Send error = %v; want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+
+			if !strings.HasSuffix(id, "@example.com") {
+				t.Errorf("message id = %q; want an @example.com id", id)
+			}
+			if from != "<noreply@example.com>" {
+				t.Errorf("MAIL FROM = %q", from)
+			}
+			if rcpt != "<jane@example.org>" {
+				t.Errorf("RCPT TO = %q", rcpt)
+			}
+
+			head, body, ok := strings.Cut(data, "\r\n\r\n")
+			if !ok {
+				t.Fatalf("message has no header/body separator: %q", data)
+			}
+			for _, h := range []string{
+				"Message-ID: <" + id + ">",
+				"Subject: =?utf-8?q?",
+				"Content-Transfer-Encoding: quoted-printable",
+			} {
+				if !strings.Contains(head, h) {
+					t.Errorf("headers missing %q:\n%s", h, head)
+				}
+			}
+			decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(strings.TrimSuffix(body, "\r\n"))))
+			if err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if string(decoded) != tt.msg.Content {
+				t.Errorf("body = %q; want %q", decoded, tt.msg.Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// ErrForbiddenDestination is returned for callback hosts that resolve to, or
+// are, an internal address.
+var ErrForbiddenDestination = errors.New("destination address is not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which
+// net.IP.IsPrivate doesn't cover.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// forbiddenIP reports addresses a tenant-supplied URL must never reach:
+// loopback, private, link-local (including cloud metadata at
+// 169.254.169.254), multicast and unspecified ones.
+func forbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip) ||
+		(ip.To4() != nil && ip.To4()[0] == 0)
+}
+
+// guardDial is a net.Dialer Control function refusing forbidden addresses.
+// It runs on the address actually being connected to, after DNS resolution,
+// so a record changed after CheckCallbackHost can't get around it.
+func guardDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || forbiddenIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, host)
+	}
+	return nil
+}
+
+// CheckCallbackHost rejects hosts that are obviously internal: IP literals
+// in forbidden ranges, localhost and single-label names such as compose
+// services. If allowed is set, host must also be one of its entries or a
+// subdomain of one. Names are only resolved when connecting.
+func CheckCallbackHost(host string, allowed []string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if ip := net.ParseIP(host); ip != nil {
+		if forbiddenIP(ip) {
+			return ErrForbiddenDestination
+		}
+	} else if host == "localhost" || strings.HasSuffix(host, ".localhost") || !strings.Contains(host, ".") {
+		return ErrForbiddenDestination
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, a := range allowed {
+		a = strings.ToLower(strings.TrimSuffix(a, "."))
+		if host == a || strings.HasSuffix(host, "."+a) {
+			return nil
+		}
+	}
+	return ErrForbiddenDestination
+}
@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+const (
+	BodyFormatJSON = "json"
+	BodyFormatForm = "form"
+)
+
+const (
+	defaultJSONTemplate = `{"to":{{json .To}},"content":{{json .Content}}}`
+	defaultFormTemplate = `to={{urlquery .To}}&content={{urlquery .Content}}`
+	defaultIDPath       = "messageId"
+)
+
+// templateData is what request body templates are rendered with. To is kept
+// as an alias of Recipient for templates written before channels existed.
+type templateData struct {
+	ID        string
+	Channel   string
+	To        string
+	Recipient string
+	Subject   string
+	Content   string
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func parseBodyTemplate(format, text string) (*template.Template, error) {
+	if text == "" {
+		switch format {
+		case BodyFormatForm:
+			text = defaultFormTemplate
+		default:
+			text = defaultJSONTemplate
+		}
+	}
+	return template.New("body").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+func contentType(format string) string {
+	if format == BodyFormatForm {
+		return "application/x-www-form-urlencoded"
+	}
+	return "application/json"
+}
+
+func (c *Client) renderBody(msg domain.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	data := templateData{
+		ID:        msg.ID,
+		Channel:   msg.Channel,
+		To:        msg.Recipient,
+		Recipient: msg.Recipient,
+		Content:   msg.Content,
+	}
+	if msg.Subject != nil {
+		data.Subject = *msg.Subject
+	}
+	if err := c.body.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	if c.cfg.BodyFormat == BodyFormatJSON && !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("body template produced invalid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// lookupPath walks a decoded JSON document along a dot separated path such as
+// "data.messages.0.id", where numeric segments index into arrays.
+func lookupPath(doc any, path string) (string, bool) {
+	cur := doc
+	for _, seg := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case map[string]any:
+			next, ok := v[seg]
+			if !ok {
+				return "", false
+			}
+			cur = next
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", false
+			}
+			cur = v[i]
+		default:
+			return "", false
+		}
+	}
+
+	switch v := cur.(type) {
+	case string:
+		return v, v != ""
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
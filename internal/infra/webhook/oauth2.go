@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+)
+
+const (
+	defaultRefreshBefore = time.Minute
+	defaultTokenLifetime = time.Hour
+)
+
+// OAuth2Config enables the OAuth2 client-credentials grant. Tokens are cached
+// in memory and, when a TokenStore is configured, shared with other replicas.
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// RefreshBefore is how long before expiry a token is refreshed in the
+	// background (default 1m).
+	RefreshBefore time.Duration
+}
+
+func (c OAuth2Config) enabled() bool {
+	return c.TokenURL != ""
+}
+
+// TokenStore shares access tokens between replicas.
+type TokenStore interface {
+	GetToken(ctx context.Context, key string) (token string, expiresAt time.Time, ok bool, err error)
+	SetToken(ctx context.Context, key, token string, expiresAt time.Time) error
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type tokenSource struct {
+	cfg   OAuth2Config
+	http  *http.Client
+	store TokenStore
+	key   string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+	inflight  *tokenFetch
+}
+
+// tokenFetch is one token request, shared by every caller that needs a new
+// token while it runs.
+type tokenFetch struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+func newTokenSource(cfg OAuth2Config, httpClient *http.Client, store TokenStore) *tokenSource {
+	if cfg.RefreshBefore == 0 {
+		cfg.RefreshBefore = defaultRefreshBefore
+	}
+	sum := sha256.Sum256([]byte(cfg.TokenURL + "|" + cfg.ClientID + "|" + strings.Join(cfg.Scopes, " ")))
+	return &tokenSource{
+		cfg:   cfg,
+		http:  httpClient,
+		store: store,
+		key:   "oauth2:token:" + hex.EncodeToString(sum[:8]),
+	}
+}
+
+// Token returns a valid access token. Tokens close to expiry are still
+// returned while a fresh one is fetched in the background; expired or
+// missing tokens are fetched synchronously.
+func (s *tokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	token, expiresAt := s.token, s.expiresAt
+	valid := token != "" && time.Now().Before(expiresAt)
+	if valid && time.Until(expiresAt) < s.cfg.RefreshBefore {
+		s.startFetchLocked(ctx, "", s.cfg.RefreshBefore)
+	}
+	s.mu.Unlock()
+
+	if valid {
+		return token, nil
+	}
+	return s.wait(ctx, "")
+}
+
+// Refresh discards stale and fetches a new token, unless another caller
+// already replaced it.
+func (s *tokenSource) Refresh(ctx context.Context, stale string) (string, error) {
+	return s.wait(ctx, stale)
+}
+
+// wait returns a valid token other than stale, joining the fetch in flight
+// or starting one.
+func (s *tokenSource) wait(ctx context.Context, stale string) (string, error) {
+	for {
+		s.mu.Lock()
+		if s.token != "" && s.token != stale && time.Now().Before(s.expiresAt) {
+			token := s.token
+			s.mu.Unlock()
+			return token, nil
+		}
+		f, started := s.startFetchLocked(ctx, stale, 0)
+		s.mu.Unlock()
+
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		// A fetch started for someone else may have settled for the token
+		// we were told is stale; ask again.
+		if started || f.err != nil || f.token != stale {
+			return f.token, f.err
+		}
+	}
+}
+
+// startFetchLocked starts fetching a token, unless a fetch is already in
+// flight, and returns the fetch to wait for. The request runs without s.mu
+// held, so callers with a valid token are never blocked by it. s.mu must be
+// held.
+func (s *tokenSource) startFetchLocked(ctx context.Context, stale string, minValid time.Duration) (*tokenFetch, bool) {
+	if s.inflight != nil {
+		return s.inflight, false
+	}
+	f := &tokenFetch{done: make(chan struct{})}
+	s.inflight = f
+
+	// The result is shared, so one caller giving up must not cancel it; the
+	// http client's timeout still bounds it.
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		token, expiresAt, err := s.obtain(ctx, stale, minValid)
+		if err != nil && minValid > 0 {
+			log.Logger.Error("oauth2 background token refresh failed", "err", err)
+		}
+
+		s.mu.Lock()
+		if err == nil {
+			s.token, s.expiresAt = token, expiresAt
+		}
+		s.inflight = nil
+		s.mu.Unlock()
+
+		f.token, f.err = token, err
+		close(f.done)
+	}()
+	return f, true
+}
+
+// obtain returns the shared token if another replica already fetched one
+// other than stale that is valid for at least minValid, and requests a new
+// one otherwise.
+func (s *tokenSource) obtain(ctx context.Context, stale string, minValid time.Duration) (string, time.Time, error) {
+	if s.store != nil {
+		token, expiresAt, ok, err := s.store.GetToken(ctx, s.key)
+		switch {
+		case err != nil:
+			log.Logger.Error("oauth2 shared token lookup failed", "err", err)
+		case ok && token != stale && time.Until(expiresAt) > minValid:
+			return token, expiresAt, nil
+		}
+	}
+	return s.fetch(ctx)
+}
+
+func (s *tokenSource) fetch(ctx context.Context) (string, time.Time, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("oauth2: build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(s.cfg.ClientSecret))
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("oauth2: token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("oauth2: token endpoint returned %d: %s", resp.StatusCode, truncate(raw, 512))
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(raw, &tr); err != nil {
+		return "", time.Time{}, fmt.Errorf("oauth2: decode token response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("oauth2: token response has no access_token")
+	}
+
+	lifetime := defaultTokenLifetime
+	if tr.ExpiresIn > 0 {
+		lifetime = time.Duration(tr.ExpiresIn) * time.Second
+	}
+	expiresAt := time.Now().Add(lifetime)
+
+	if s.store != nil {
+		if err := s.store.SetToken(ctx, s.key, tr.AccessToken, expiresAt); err != nil {
+			log.Logger.Error("oauth2 shared token store failed", "err", err)
+		}
+	}
+	log.Logger.Info("oauth2 token fetched", "expires_at", expiresAt.UTC().Format(time.RFC3339))
+	return tr.AccessToken, expiresAt, nil
+}
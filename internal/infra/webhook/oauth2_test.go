@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+// tokenServer hands out tok-1, tok-2, ... with the configured lifetime. A
+// request blocks while gate is set, until the gate is closed.
+type tokenServer struct {
+	*httptest.Server
+	calls     atomic.Int32
+	expiresIn int
+	gate      chan struct{}
+}
+
+func newTokenServer(t *testing.T, expiresIn int) *tokenServer {
+	t.Helper()
+	ts := &tokenServer{expiresIn: expiresIn}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "client" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := ts.calls.Add(1)
+		if ts.gate != nil {
+			<-ts.gate
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"tok-%d","token_type":"Bearer","expires_in":%d}`, n, ts.expiresIn)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *tokenServer) source(refreshBefore time.Duration) *tokenSource {
+	return newTokenSource(OAuth2Config{
+		TokenURL:      ts.URL,
+		ClientID:      "client",
+		ClientSecret:  "secret",
+		RefreshBefore: refreshBefore,
+	}, &http.Client{Timeout: 5 * time.Second}, nil)
+}
+
+func TestTokenSourceCaches(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	src := ts.source(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		token, err := src.Token(context.Background())
+		if err != nil {
+			t.Fatalf("Token: %v", err)
+		}
+		if token != "tok-1" {
+			t.Errorf("token = %q; want tok-1", token)
+		}
+	}
+	if n := ts.calls.Load(); n != 1 {
+		t.Errorf("token endpoint called %d times; want 1", n)
+	}
+}
+
+func TestTokenSourceSharesOneFetch(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	ts.gate = make(chan struct{})
+	src := ts.source(time.Minute)
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 10)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], _ = src.Token(context.Background())
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(ts.gate)
+	wg.Wait()
+
+	for i, token := range tokens {
+		if token != "tok-1" {
+			t.Errorf("caller %d got %q; want tok-1", i, token)
+		}
+	}
+	if n := ts.calls.Load(); n != 1 {
+		t.Errorf("token endpoint called %d times; want 1", n)
+	}
+}
+
+func TestTokenSourceRefreshesBeforeExpiry(t *testing.T) {
+	// Every token is within RefreshBefore of expiry as soon as it arrives.
+	ts := newTokenServer(t, 60)
+	src := ts.source(2 * time.Minute)
+
+	token, err := src.Token(context.Background())
+	if err != nil || token != "tok-1" {
+		t.Fatalf("Token = %q, %v; want tok-1", token, err)
+	}
+
+	// The background refresh must not hold up callers while the token
+	// endpoint is slow.
+	ts.gate = make(chan struct{})
+	start := time.Now()
+	token, err = src.Token(context.Background())
+	if err != nil || token != "tok-1" {
+		t.Fatalf("Token during refresh = %q, %v; want tok-1", token, err)
+	}
+	token, _ = src.Token(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Token blocked for %s during a background refresh", elapsed)
+	}
+	if token != "tok-1" {
+		t.Errorf("second Token during refresh = %q; want tok-1", token)
+	}
+	close(ts.gate)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		token, _ = src.Token(context.Background())
+		if token == "tok-2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("token still %q after the background refresh", token)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientRetriesOnceOnUnauthorized(t *testing.T) {
+	tests := []struct {
+		name       string
+		accept     string
+		wantErr    bool
+		wantTokens int32
+		wantSends  int32
+	}{
+		{name: "revoked token is replaced", accept: "tok-2", wantTokens: 2, wantSends: 2},
+		{name: "still unauthorized after refresh", accept: "none", wantErr: true, wantTokens: 2, wantSends: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTokenServer(t, 3600)
+			var sends atomic.Int32
+			provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sends.Add(1)
+				if r.Header.Get("Authorization") != "Bearer "+tt.accept {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(http.StatusAccepted)
+				fmt.Fprint(w, `{"messageId":"p-1"}`)
+			}))
+			defer provider.Close()
+
+			c, err := NewClient(Config{
+				URL:    provider.URL,
+				OAuth2: OAuth2Config{TokenURL: ts.URL, ClientID: "client", ClientSecret: "secret"},
+			})
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+
+			id, err := c.Send(context.Background(), domain.Message{ID: "m-1", Recipient: "+905551234567", Content: "hi"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send error = %v; want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && id != "p-1" {
+				t.Errorf("provider id = %q; want p-1", id)
+			}
+			if n := ts.calls.Load(); n != tt.wantTokens {
+				t.Errorf("token endpoint called %d times; want %d", n, tt.wantTokens)
+			}
+			if n := sends.Load(); n != tt.wantSends {
+				t.Errorf("provider called %d times; want %d", n, tt.wantSends)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// OutboxEventIDHeader carries the outbox id of a relayed event; receivers
+// de-duplicate redeliveries on it.
+const OutboxEventIDHeader = "X-Outbox-Event-ID"
+
+// OutboxWebhook implements domain.OutboxSink by POSTing each event's
+// payload, signed like status callbacks, to a fixed URL.
+type OutboxWebhook struct {
+	client *StatusCallbackClient
+	url    string
+}
+
+func NewOutboxWebhook(url string, cfg StatusCallbackConfig) (*OutboxWebhook, error) {
+	if url == "" {
+		return nil, fmt.Errorf("outbox webhook needs a URL")
+	}
+	if len(cfg.SigningSecrets) == 0 {
+		return nil, fmt.Errorf("outbox webhook needs a signing secret")
+	}
+	// The URL comes from the operator, who may well point it at an
+	// internal consumer.
+	cfg.AllowPrivateNetworks = true
+	client, err := NewStatusCallbackClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxWebhook{client: client, url: url}, nil
+}
+
+func (o *OutboxWebhook) Publish(ctx context.Context, ev domain.OutboxEvent) error {
+	header := http.Header{}
+	header.Set(OutboxEventIDHeader, strconv.FormatInt(ev.ID, 10))
+	// Our own consumer may join the trace; tenant callbacks never get it.
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+	code, err := o.client.post(ctx, o.url, o.client.cfg.SigningSecrets, ev.Payload, header)
+	if err != nil {
+		return err
+	}
+	if code < 200 || code >= 300 {
+		return fmt.Errorf("unexpected status %d", code)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// Reachable dials the provider host without sending a request, so readiness
+// probes don't create messages or burn rate limits.
+func (c *Client) Reachable(ctx context.Context) error {
+	u, err := url.Parse(c.cfg.URL)
+	if err != nil {
+		return fmt.Errorf("parse url: %w", err)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/pkg/webhooksig"
+)
+
+// StatusCallbackConfig configures the client POSTing status callbacks to
+// the URLs given by message creators.
+type StatusCallbackConfig struct {
+	Timeout time.Duration
+	// SigningSecrets sign requests that don't bring their own, i.e. the
+	// outbox webhook; status callbacks are signed with their tenant's.
+	SigningSecrets  []string
+	SignatureHeader string
+	// AllowedHosts, if set, limits callbacks to these hosts and their
+	// subdomains.
+	AllowedHosts []string
+	// AllowPrivateNetworks lifts the ban on internal addresses; only for
+	// URLs configured by the operator, never for tenant-supplied ones.
+	AllowPrivateNetworks bool
+}
+
+// StatusCallbackClient implements domain.CallbackPoster.
+type StatusCallbackClient struct {
+	http *http.Client
+	cfg  StatusCallbackConfig
+}
+
+func NewStatusCallbackClient(cfg StatusCallbackConfig) (*StatusCallbackClient, error) {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = webhooksig.DefaultHeader
+	}
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = guardDial
+	}
+	return &StatusCallbackClient{
+		http: &http.Client{
+			Timeout: cfg.Timeout,
+			// No proxy: it would connect on our behalf, past guardDial.
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: cfg.Timeout,
+				MaxIdleConnsPerHost: 2,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			// A redirect would carry the signed payload to a URL the creator
+			// didn't give us; treat it as a failed attempt instead.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg: cfg,
+	}, nil
+}
+
+// Post signs payload with secrets, the tenant's callback signing secrets.
+func (c *StatusCallbackClient) Post(ctx context.Context, url string, secrets []string, payload []byte) (int, error) {
+	return c.post(ctx, url, secrets, payload, nil)
+}
+
+func (c *StatusCallbackClient) post(ctx context.Context, rawURL string, secrets []string, payload []byte, header http.Header) (int, error) {
+	if len(secrets) == 0 {
+		// Never send unsigned: the receiver couldn't tell it from a forgery.
+		return 0, fmt.Errorf("no signing secret configured")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, fmt.Errorf("parse url: %w", err)
+	}
+	if !c.cfg.AllowPrivateNetworks {
+		// URLs stored before a policy change are checked again here.
+		if err := CheckCallbackHost(u.Hostname(), c.cfg.AllowedHosts); err != nil {
+			return 0, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("build request: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(c.cfg.SignatureHeader, webhooksig.Sign(payload, time.Now(), secrets...))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("http send: %w", err)
+	}
+	defer resp.Body.Close()
+	// Drain a little so the connection can be reused; the body is ignored.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,229 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+)
+
+// TLSConfig configures mutual TLS towards the provider. Certificate and CA
+// files are re-read whenever their modification time changes, so rotated
+// certificates are picked up without a restart.
+type TLSConfig struct {
+	CertFile   string
+	KeyFile    string
+	CAFile     string
+	MinVersion string // "1.2" (default) or "1.3"
+	ServerName string
+}
+
+func (c TLSConfig) enabled() bool {
+	return c.CertFile != "" || c.CAFile != "" || c.ServerName != "" || c.MinVersion != ""
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+type tlsSource struct {
+	cfg        TLSConfig
+	minVersion uint16
+	onReload   func()
+
+	mu       sync.Mutex
+	modTimes map[string]time.Time
+	current  *tls.Config
+}
+
+func newTLSSource(cfg TLSConfig) (*tlsSource, error) {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, fmt.Errorf("tls: cert file and key file must be set together")
+	}
+	minVersion := uint16(tls.VersionTLS12)
+	if cfg.MinVersion != "" {
+		v, ok := tlsVersions[cfg.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("tls: unsupported min version %q", cfg.MinVersion)
+		}
+		minVersion = v
+	}
+
+	s := &tlsSource{cfg: cfg, minVersion: minVersion, modTimes: map[string]time.Time{}}
+	if _, err := s.config(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// config returns the current tls.Config, reloading it first if any of the
+// files changed. A failed reload keeps serving the previous config.
+func (s *tlsSource) config() (*tls.Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current != nil && !s.changed() {
+		return s.current, nil
+	}
+
+	cfg, err := s.load()
+	if err != nil {
+		if s.current == nil {
+			return nil, err
+		}
+		log.Logger.Error("webhook tls reload failed, keeping previous certificates", "err", err)
+		return s.current, nil
+	}
+
+	reloaded := s.current != nil
+	s.current = cfg
+	if reloaded {
+		log.Logger.Info("webhook tls certificates reloaded")
+		if s.onReload != nil {
+			s.onReload()
+		}
+	}
+	return s.current, nil
+}
+
+func (s *tlsSource) changed() bool {
+	for _, f := range s.files() {
+		fi, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		if !fi.ModTime().Equal(s.modTimes[f]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *tlsSource) load() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: s.minVersion,
+		ServerName: s.cfg.ServerName,
+	}
+
+	if s.cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.cfg.CertFile, s.cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if s.cfg.CAFile != "" {
+		pem, err := os.ReadFile(s.cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: read ca bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls: no certificates found in %s", s.cfg.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	for _, f := range s.files() {
+		if fi, err := os.Stat(f); err == nil {
+			s.modTimes[f] = fi.ModTime()
+		}
+	}
+	return cfg, nil
+}
+
+func (s *tlsSource) files() []string {
+	var out []string
+	for _, f := range []string{s.cfg.CertFile, s.cfg.KeyFile, s.cfg.CAFile} {
+		if f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// reloadingTransport checks for changed certificate files before every
+// request; a reload drops idle connections so the next request redials with
+// the new certificates instead of reusing a keep-alive connection.
+type reloadingTransport struct {
+	src  *tlsSource
+	base *http.Transport
+}
+
+func (t *reloadingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, err := t.src.config(); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// clientCertificate presents the current client certificate, reloading it
+// first if its files changed.
+func (s *tlsSource) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cfg, err := s.config()
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Certificates) == 0 {
+		return &tls.Certificate{}, nil
+	}
+	return &cfg.Certificates[0], nil
+}
+
+// verifyConnection checks the server chain against the current CA bundle.
+// It replaces the verification crypto/tls would do against a pool fixed at
+// construction time.
+func (s *tlsSource) verifyConnection(cs tls.ConnectionState) error {
+	cfg, err := s.config()
+	if err != nil {
+		return err
+	}
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("tls: server presented no certificate")
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         cfg.RootCAs,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	_, err = cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// newTLSTransport returns a transport whose TLS handshakes use the latest
+// tlsSource config. Certificates are resolved per handshake through
+// TLSClientConfig callbacks rather than a custom dialer, so they also apply
+// to connections tunnelled through an HTTPS proxy and HTTP/2 keeps working.
+func newTLSTransport(cfg TLSConfig) (http.RoundTripper, error) {
+	src, err := newTLSSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{
+		MinVersion:           src.minVersion,
+		ServerName:           cfg.ServerName,
+		GetClientCertificate: src.clientCertificate,
+	}
+	if cfg.CAFile != "" {
+		// The CA bundle can be rotated, so the chain is verified against
+		// the current pool in VerifyConnection instead of a fixed RootCAs.
+		t.TLSClientConfig.InsecureSkipVerify = true
+		t.TLSClientConfig.VerifyConnection = src.verifyConnection
+	}
+	src.onReload = t.CloseIdleConnections
+	return &reloadingTransport{src: src, base: t}, nil
+}
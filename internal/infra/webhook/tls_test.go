@@ -0,0 +1,201 @@
+package webhook
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+// writeClientCert writes a self-signed client certificate with the given
+// common name to certFile and keyFile.
+func writeClientCert(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A rewrite must change the modification time even on file systems
+	// with coarse timestamps.
+	mod := time.Now()
+	if fi, err := os.Stat(certFile); err == nil {
+		mod = fi.ModTime().Add(time.Second)
+	}
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// newMTLSProvider starts a provider requiring a client certificate, which
+// answers with the certificate's common name as the message id.
+func newMTLSProvider(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, `{"messageId":%q}`, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return srv, caFile
+}
+
+// newConnectProxy starts an HTTP proxy that only tunnels CONNECT requests.
+func newConnectProxy(t *testing.T, tunnels *atomic.Int32) *httptest.Server {
+	t.Helper()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "only CONNECT", http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		client, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		tunnels.Add(1)
+		io.WriteString(client, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go func() {
+			io.Copy(upstream, client)
+			upstream.Close()
+		}()
+		io.Copy(client, upstream)
+		client.Close()
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	tests := []struct {
+		name      string
+		viaProxy  bool
+		rotate    bool
+		wantFirst string
+		wantAfter string
+	}{
+		{name: "direct", wantFirst: "client-1"},
+		{name: "through an https proxy", viaProxy: true, wantFirst: "client-1"},
+		{name: "reloads a rewritten certificate", rotate: true, wantFirst: "client-1", wantAfter: "client-2"},
+		{name: "reloads through an https proxy", viaProxy: true, rotate: true, wantFirst: "client-1", wantAfter: "client-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, caFile := newMTLSProvider(t)
+			dir := t.TempDir()
+			certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+			writeClientCert(t, certFile, keyFile, "client-1")
+
+			c, err := NewClient(Config{
+				URL: srv.URL,
+				TLS: TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: caFile, ServerName: "example.com"},
+			})
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			var tunnels atomic.Int32
+			if tt.viaProxy {
+				proxy := newConnectProxy(t, &tunnels)
+				proxyURL, _ := url.Parse(proxy.URL)
+				c.http.Transport.(*reloadingTransport).base.Proxy = http.ProxyURL(proxyURL)
+			}
+
+			send := func() string {
+				t.Helper()
+				id, err := c.Send(context.Background(), domain.Message{ID: "m-1", Recipient: "+905551234567", Content: "hi"})
+				if err != nil {
+					t.Fatalf("Send: %v", err)
+				}
+				return id
+			}
+
+			if got := send(); got != tt.wantFirst {
+				t.Errorf("presented certificate %q; want %q", got, tt.wantFirst)
+			}
+			if tt.rotate {
+				writeClientCert(t, certFile, keyFile, "client-2")
+				if got := send(); got != tt.wantAfter {
+					t.Errorf("after rotation presented certificate %q; want %q", got, tt.wantAfter)
+				}
+			}
+			if tt.viaProxy && tunnels.Load() == 0 {
+				t.Error("request did not go through the proxy")
+			}
+		})
+	}
+}
+
+func TestTLSRejectsUnknownServer(t *testing.T) {
+	srv, _ := newMTLSProvider(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	writeClientCert(t, certFile, keyFile, "client-1")
+	// Any certificate that did not issue the server's will do as the bundle.
+	otherCA := filepath.Join(dir, "other-ca.pem")
+	writeClientCert(t, otherCA, filepath.Join(dir, "other-ca-key.pem"), "other-ca")
+
+	c, err := NewClient(Config{
+		URL: srv.URL,
+		TLS: TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: otherCA, ServerName: "example.com"},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if _, err := c.Send(context.Background(), domain.Message{ID: "m-1", Recipient: "+905551234567", Content: "hi"}); err == nil {
+		t.Fatal("Send succeeded against a server outside the CA bundle")
+	}
+}
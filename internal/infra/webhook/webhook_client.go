@@ -7,85 +7,222 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/temo927/go-msg-dispatcher/internal/domain"
 	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/metrics"
+	"github.com/temo927/go-msg-dispatcher/pkg/webhooksig"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
+const maxResponseBody = 64 << 10
+
 type Config struct {
 	URL          string
 	AuthHeader   string
 	AuthValue    string
 	AcceptAny2xx bool
 	Timeout      time.Duration
+
+	// SigningSecrets enables HMAC request signing (see pkg/webhooksig). Every
+	// secret in the list signs the request so keys can be rotated by adding
+	// the new secret first and removing the old one once receivers caught up.
+	SigningSecrets  []string
+	SignatureHeader string
+
+	// Method and Headers default to POST and no extra headers.
+	Method  string
+	Headers map[string]string
+	// BodyFormat is "json" (default) or "form". BodyTemplate is a text/template
+	// rendered with .ID, .Channel, .To (.Recipient), .Subject and .Content; it
+	// defaults to {"to":..,"content":..} for JSON and to=..&content=.. for form
+	// bodies.
+	BodyFormat   string
+	BodyTemplate string
+	// ResponseIDPath is the dot path of the provider message id in the JSON
+	// response (default "messageId"). ResponseErrorPath optionally points at a
+	// provider error code reported on rejected requests.
+	ResponseIDPath    string
+	ResponseErrorPath string
+
+	TLS TLSConfig
+
+	OAuth2 OAuth2Config
+	// TokenStore optionally shares OAuth2 tokens between replicas.
+	TokenStore TokenStore
 }
 
 type Client struct {
-	http *http.Client
-	cfg  Config
+	http   *http.Client
+	cfg    Config
+	body   *template.Template
+	tokens *tokenSource
+}
+
+// ProviderError is returned when the provider rejects a request.
+type ProviderError struct {
+	StatusCode int
+	Code       string
+	Body       string
 }
 
-func NewClient(cfg Config) *Client {
+func (e *ProviderError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("unexpected status %d (code %s): %s", e.StatusCode, e.Code, e.Body)
+	}
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+// ErrorCode tells throttling and provider outages apart from rejections.
+func (e *ProviderError) ErrorCode() string {
+	if e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500 {
+		return domain.SendErrorProviderUnavailable
+	}
+	return domain.SendErrorProviderRejected
+}
+
+func NewClient(cfg Config) (*Client, error) {
 	if cfg.Timeout == 0 {
 		cfg.Timeout = 5 * time.Second 
 	}
-	return &Client{
-		http: &http.Client{Timeout: cfg.Timeout},
-		cfg:  cfg,
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = webhooksig.DefaultHeader
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodPost
+	}
+	if cfg.BodyFormat == "" {
+		cfg.BodyFormat = BodyFormatJSON
+	}
+	if cfg.BodyFormat != BodyFormatJSON && cfg.BodyFormat != BodyFormatForm {
+		return nil, fmt.Errorf("unsupported body format %q", cfg.BodyFormat)
+	}
+	if cfg.ResponseIDPath == "" {
+		cfg.ResponseIDPath = defaultIDPath
 	}
-}
 
-type webhookPayload struct {
-	To      string `json:"to"`
-	Content string `json:"content"`
-}
+	body, err := parseBodyTemplate(cfg.BodyFormat, cfg.BodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse body template: %w", err)
+	}
 
-type webhookResponse struct {
-	Message   string `json:"message"`
-	MessageID string `json:"messageId"`
+	httpClient := &http.Client{Timeout: cfg.Timeout}
+	if cfg.TLS.enabled() {
+		transport, err := newTLSTransport(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = transport
+	}
+
+	c := &Client{
+		http: httpClient,
+		cfg:  cfg,
+		body: body,
+	}
+	if cfg.OAuth2.enabled() {
+		// The token endpoint gets a plain client: the provider's client
+		// certificate and private CA are not meant for it.
+		c.tokens = newTokenSource(cfg.OAuth2, &http.Client{Timeout: cfg.Timeout}, cfg.TokenStore)
+	}
+	return c, nil
 }
 
 func (c *Client) Send(ctx context.Context, msg domain.Message) (string, error) {
-	body, err := json.Marshal(webhookPayload{
-		To:      msg.ToPhone,
-		Content: msg.Content,
-	})
+	body, err := c.renderBody(msg)
 	if err != nil {
-		return "", fmt.Errorf("marshal payload: %w", err)
+		return "", fmt.Errorf("render payload: %w", err)
+	}
+
+	var token string
+	if c.tokens != nil {
+		if token, err = c.tokens.Token(ctx); err != nil {
+			return "", err
+		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+	resp, err := c.do(ctx, body, token)
 	if err != nil {
-		return "", fmt.Errorf("build request: %w", err)
+		return "", err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	if c.cfg.AuthHeader != "" && c.cfg.AuthValue != "" {
-		req.Header.Set(c.cfg.AuthHeader, c.cfg.AuthValue)
+	if resp.StatusCode == http.StatusUnauthorized && c.tokens != nil {
+		// The token may have been revoked early; retry once with a fresh one.
+		resp.Body.Close()
+		if token, err = c.tokens.Refresh(ctx, token); err != nil {
+			return "", err
+		}
+		if resp, err = c.do(ctx, body, token); err != nil {
+			return "", err
+		}
 	}
+	defer resp.Body.Close()
 
-	resp, err := c.http.Do(req)
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
 	if err != nil {
-		return "", fmt.Errorf("http send: %w", err)
+		return "", fmt.Errorf("read response: %w", err)
 	}
-	defer resp.Body.Close()
+	doc := decodeResponse(raw)
 
 	if !c.acceptedStatus(resp.StatusCode) {
-		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
-		log.Logger.Error("webhook non-2xx", "status", resp.StatusCode, "body", string(b))
-		return "", fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(b))
+		perr := &ProviderError{StatusCode: resp.StatusCode, Body: scrub(truncate(raw, 512), msg)}
+		if c.cfg.ResponseErrorPath != "" {
+			perr.Code, _ = lookupPath(doc, c.cfg.ResponseErrorPath)
+		}
+		log.Logger.ErrorContext(ctx, "webhook non-2xx", "msg_id", msg.ID, "status", resp.StatusCode, "code", perr.Code, "body", perr.Body)
+		return "", perr
+	}
+
+	if doc == nil {
+		return "", fmt.Errorf("decode response: invalid JSON")
 	}
+	providerID, ok := lookupPath(doc, c.cfg.ResponseIDPath)
+	if !ok {
+		if c.cfg.ResponseErrorPath != "" {
+			if code, ok := lookupPath(doc, c.cfg.ResponseErrorPath); ok {
+				return "", &ProviderError{StatusCode: resp.StatusCode, Code: code, Body: scrub(truncate(raw, 512), msg)}
+			}
+		}
+		return "", fmt.Errorf("missing %s in webhook response", c.cfg.ResponseIDPath)
+	}
+
+	log.Logger.InfoContext(ctx, "webhook accepted", "msg_id", msg.ID, "provider_message_id", providerID)
+	return providerID, nil
+}
 
-	var res webhookResponse
-	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		return "", fmt.Errorf("decode response: %w", err)
+func (c *Client) do(ctx context.Context, body []byte, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, c.cfg.Method, c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType(c.cfg.BodyFormat))
+	for k, v := range c.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if c.cfg.AuthHeader != "" && c.cfg.AuthValue != "" {
+		req.Header.Set(c.cfg.AuthHeader, c.cfg.AuthValue)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
-	if res.MessageID == "" {
-		return "", fmt.Errorf("missing messageId in webhook response")
+	if len(c.cfg.SigningSecrets) > 0 {
+		req.Header.Set(c.cfg.SignatureHeader, webhooksig.Sign(body, time.Now(), c.cfg.SigningSecrets...))
 	}
+	// Lets providers that trace join our trace (traceparent/tracestate).
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
-	log.Logger.Info("webhook accepted", "msg_id", msg.ID, "provider_message_id", res.MessageID)
-	return res.MessageID, nil
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		metrics.ProviderLatency.WithLabelValues(req.URL.Host, "error").Observe(time.Since(start).Seconds())
+		return nil, fmt.Errorf("http send: %w", err)
+	}
+	metrics.ProviderLatency.WithLabelValues(req.URL.Host, strconv.Itoa(resp.StatusCode)).Observe(time.Since(start).Seconds())
+	return resp, nil
 }
 
 func (c *Client) acceptedStatus(code int) bool {
@@ -97,3 +234,36 @@ func (c *Client) acceptedStatus(code int) bool {
 	}
 	return false
 }
+
+// decodeResponse returns the decoded JSON body, or nil if it isn't JSON.
+func decodeResponse(raw []byte) any {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil
+	}
+	return doc
+}
+
+func truncate(b []byte, n int) string {
+	if len(b) > n {
+		b = b[:n]
+	}
+	return string(b)
+}
+
+// scrub removes the message's own content and recipient from a provider
+// response before it ends up in errors and logs; providers often echo them.
+func scrub(body string, msg domain.Message) string {
+	for _, s := range []string{msg.Content, msg.Recipient} {
+		if len(s) < 4 {
+			continue
+		}
+		body = strings.ReplaceAll(body, s, "[redacted]")
+		if esc, err := json.Marshal(s); err == nil {
+			body = strings.ReplaceAll(body, string(esc[1:len(esc)-1]), "[redacted]")
+		}
+	}
+	return body
+}
@@ -0,0 +1,84 @@
+// Package msgtemplate renders message templates with {{variable}} placeholders.
+package msgtemplate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+var ErrSyntax = errors.New("invalid template")
+
+// MissingVariablesError lists the variables a render call didn't supply.
+type MissingVariablesError struct {
+	Names []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return "missing template variables: " + strings.Join(e.Names, ", ")
+}
+
+// Validate reports bodies with stray or malformed {{ }} markers.
+func Validate(body string) error {
+	rest := placeholder.ReplaceAllString(body, "")
+	if strings.Contains(rest, "{{") || strings.Contains(rest, "}}") {
+		return fmt.Errorf("%w: placeholders must look like {{name}}", ErrSyntax)
+	}
+	return nil
+}
+
+// Variables returns the sorted, de-duplicated placeholder names in body.
+func Variables(body string) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, m := range placeholder.FindAllStringSubmatch(body, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render substitutes every placeholder in body. It fails with a
+// *MissingVariablesError if any placeholder has no value in vars.
+func Render(body string, vars map[string]string) (string, error) {
+	var missing []string
+	for _, name := range Variables(body) {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", &MissingVariablesError{Names: missing}
+	}
+
+	return placeholder.ReplaceAllStringFunc(body, func(m string) string {
+		return vars[placeholder.FindStringSubmatch(m)[1]]
+	}), nil
+}
+
+// PickLocale chooses the body for locale from bodies: an exact match first,
+// then the base language ("tr" for "tr-TR"), then defaultLocale. ok is false
+// if none of them has a body.
+func PickLocale(bodies map[string]string, locale, defaultLocale string) (picked, body string, ok bool) {
+	if locale != "" {
+		if b, ok := bodies[locale]; ok {
+			return locale, b, true
+		}
+		if base, _, ok := strings.Cut(locale, "-"); ok {
+			if b, ok := bodies[base]; ok {
+				return base, b, true
+			}
+		}
+	}
+	if b, ok := bodies[defaultLocale]; ok {
+		return defaultLocale, b, true
+	}
+	return "", "", false
+}
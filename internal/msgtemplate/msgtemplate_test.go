@@ -0,0 +1,111 @@
+package msgtemplate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPickLocale(t *testing.T) {
+	bodies := map[string]string{
+		"en":    "Hello",
+		"tr":    "Merhaba",
+		"pt-BR": "Olá",
+	}
+
+	tests := []struct {
+		name          string
+		bodies        map[string]string
+		locale        string
+		defaultLocale string
+		wantLocale    string
+		wantBody      string
+		wantOK        bool
+	}{
+		{name: "exact match", bodies: bodies, locale: "tr", defaultLocale: "en", wantLocale: "tr", wantBody: "Merhaba", wantOK: true},
+		{name: "exact regional match", bodies: bodies, locale: "pt-BR", defaultLocale: "en", wantLocale: "pt-BR", wantBody: "Olá", wantOK: true},
+		{name: "base language", bodies: bodies, locale: "tr-TR", defaultLocale: "en", wantLocale: "tr", wantBody: "Merhaba", wantOK: true},
+		{name: "unknown locale falls back to default", bodies: bodies, locale: "de", defaultLocale: "en", wantLocale: "en", wantBody: "Hello", wantOK: true},
+		{name: "unknown region and base fall back to default", bodies: bodies, locale: "de-AT", defaultLocale: "en", wantLocale: "en", wantBody: "Hello", wantOK: true},
+		{name: "no base for regional-only body", bodies: bodies, locale: "pt", defaultLocale: "en", wantLocale: "en", wantBody: "Hello", wantOK: true},
+		{name: "empty locale uses default", bodies: bodies, locale: "", defaultLocale: "tr", wantLocale: "tr", wantBody: "Merhaba", wantOK: true},
+		{name: "default missing", bodies: bodies, locale: "de", defaultLocale: "fr", wantOK: false},
+		{name: "no bodies", bodies: nil, locale: "en", defaultLocale: "en", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locale, body, ok := PickLocale(tt.bodies, tt.locale, tt.defaultLocale)
+			if locale != tt.wantLocale || body != tt.wantBody || ok != tt.wantOK {
+				t.Errorf("PickLocale(%q, %q) = %q, %q, %v; want %q, %q, %v",
+					tt.locale, tt.defaultLocale, locale, body, ok, tt.wantLocale, tt.wantBody, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		vars        map[string]string
+		want        string
+		wantMissing []string
+	}{
+		{name: "no placeholders", body: "Hi there", want: "Hi there"},
+		{name: "placeholder", body: "Hi {{name}}", vars: map[string]string{"name": "Ada"}, want: "Hi Ada"},
+		{name: "spaces inside braces", body: "Hi {{ name }}", vars: map[string]string{"name": "Ada"}, want: "Hi Ada"},
+		{name: "repeated placeholder", body: "{{code}}/{{code}}", vars: map[string]string{"code": "42"}, want: "42/42"},
+		{name: "extra variables are ignored", body: "Hi {{name}}", vars: map[string]string{"name": "Ada", "x": "y"}, want: "Hi Ada"},
+		{name: "empty value", body: "{{name}}", vars: map[string]string{"name": ""}, want: ""},
+		{name: "missing variables are listed sorted", body: "{{b}} {{a}} {{b}}", wantMissing: []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.body, tt.vars)
+			if tt.wantMissing != nil {
+				var missing *MissingVariablesError
+				if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Names, tt.wantMissing) {
+					t.Fatalf("Render() error = %v, want missing %v", err, tt.wantMissing)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		body    string
+		wantErr bool
+	}{
+		{body: "Hi {{name}}, your code is {{ code }}"},
+		{body: "no placeholders"},
+		{body: "Hi {{name}", wantErr: true},
+		{body: "Hi name}}", wantErr: true},
+		{body: "Hi {{first name}}", wantErr: true},
+		{body: "Hi {{1st}}", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.body)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%q) = %v, wantErr %v", tt.body, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrSyntax) {
+			t.Errorf("Validate(%q) = %v, want ErrSyntax", tt.body, err)
+		}
+	}
+}
+
+func TestVariables(t *testing.T) {
+	got := Variables("{{b}} {{ a }} {{b}} {{c_1}}")
+	want := []string{"a", "b", "c_1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Variables() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,129 @@
+// Package phone validates and normalises phone numbers to E.164.
+//
+// It is deliberately small: it knows the country code, trunk prefix and
+// national number length of the regions we send to, and falls back to the
+// generic E.164 rules (max 15 digits, no leading zero) for everything else.
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalid = errors.New("invalid phone number")
+
+type region struct {
+	countryCode string
+	trunkPrefix string
+	minLen      int // national significant number length
+	maxLen      int
+	// trunkInNumber is set where national numbers may themselves start with
+	// the trunk prefix (RU 812..., 800...), so it is only stripped from
+	// numbers too long without it.
+	trunkInNumber bool
+}
+
+var regions = map[string]region{
+	"AE": {"971", "0", 8, 9, false},
+	"AZ": {"994", "0", 9, 9, false},
+	"CA": {"1", "1", 10, 10, false},
+	"DE": {"49", "0", 6, 13, false},
+	"ES": {"34", "", 9, 9, false},
+	"FR": {"33", "0", 9, 9, false},
+	"GB": {"44", "0", 9, 10, false},
+	"GE": {"995", "0", 9, 9, false},
+	"GR": {"30", "", 10, 10, false},
+	"IN": {"91", "0", 10, 10, false},
+	"IT": {"39", "", 6, 11, false},
+	"NL": {"31", "0", 9, 9, false},
+	"RU": {"7", "8", 10, 10, true},
+	"SA": {"966", "0", 9, 9, false},
+	"TR": {"90", "0", 10, 10, false},
+	"UA": {"380", "0", 9, 9, false},
+	"US": {"1", "1", 10, 10, false},
+}
+
+// byCountryCode maps a calling code to its length rules. Regions sharing a
+// code (US/CA) share the same rules.
+var byCountryCode = func() map[string]region {
+	m := make(map[string]region, len(regions))
+	for _, r := range regions {
+		m[r.countryCode] = r
+	}
+	return m
+}()
+
+// SupportedRegion reports whether code (ISO 3166-1 alpha-2) can be used as a
+// default region for national numbers.
+func SupportedRegion(code string) bool {
+	_, ok := regions[strings.ToUpper(code)]
+	return ok
+}
+
+// Normalize returns raw in E.164 form ("+905551234567"). Numbers starting with
+// "+" or "00" are treated as international; anything else is a national
+// number in defaultRegion. Spaces, dashes, dots and parentheses are ignored.
+func Normalize(raw, defaultRegion string) (string, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return "", fmt.Errorf("%w: empty", ErrInvalid)
+	}
+
+	international := false
+	switch {
+	case strings.HasPrefix(s, "+"):
+		international = true
+		s = s[1:]
+	case strings.HasPrefix(s, "00"):
+		international = true
+		s = s[2:]
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", fmt.Errorf("%w: unexpected character %q", ErrInvalid, r)
+		}
+	}
+	digits := b.String()
+
+	if !international {
+		reg, ok := regions[strings.ToUpper(defaultRegion)]
+		if !ok {
+			return "", fmt.Errorf("%w: national number without a known default region", ErrInvalid)
+		}
+		if reg.trunkPrefix != "" && (!reg.trunkInNumber || len(digits) > reg.maxLen) {
+			digits = strings.TrimPrefix(digits, reg.trunkPrefix)
+		}
+		if len(digits) < reg.minLen || len(digits) > reg.maxLen {
+			return "", fmt.Errorf("%w: wrong length for a %s number", ErrInvalid, strings.ToUpper(defaultRegion))
+		}
+		digits = reg.countryCode + digits
+	}
+
+	if len(digits) < 8 || len(digits) > 15 {
+		return "", fmt.Errorf("%w: must have 8 to 15 digits", ErrInvalid)
+	}
+	if digits[0] == '0' {
+		return "", fmt.Errorf("%w: country code cannot start with 0", ErrInvalid)
+	}
+
+	for n := 3; n >= 1; n-- {
+		reg, ok := byCountryCode[digits[:n]]
+		if !ok {
+			continue
+		}
+		nsn := len(digits) - n
+		if nsn < reg.minLen || nsn > reg.maxLen {
+			return "", fmt.Errorf("%w: wrong length for country code +%s", ErrInvalid, reg.countryCode)
+		}
+		break
+	}
+
+	return "+" + digits, nil
+}
@@ -0,0 +1,76 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		region string
+		want   string
+	}{
+		{name: "international with plus", raw: "+90 555 123 45 67", region: "TR", want: "+905551234567"},
+		{name: "international with 00", raw: "00905551234567", region: "TR", want: "+905551234567"},
+		{name: "international ignores default region", raw: "+1 415 555 2671", region: "TR", want: "+14155552671"},
+		{name: "TR trunk 0", raw: "0555 123 45 67", region: "TR", want: "+905551234567"},
+		{name: "TR without trunk", raw: "555-123-45-67", region: "TR", want: "+905551234567"},
+		{name: "region is case-insensitive", raw: "05551234567", region: "tr", want: "+905551234567"},
+		{name: "RU trunk 8", raw: "8 (912) 345-67-89", region: "RU", want: "+79123456789"},
+		{name: "RU area code starting with 8", raw: "(812) 345-67-89", region: "RU", want: "+78123456789"},
+		{name: "RU toll-free without trunk", raw: "800 555-35-35", region: "RU", want: "+78005553535"},
+		{name: "RU toll-free with trunk", raw: "8 800 555-35-35", region: "RU", want: "+78005553535"},
+		{name: "US trunk 1", raw: "1 (415) 555-2671", region: "US", want: "+14155552671"},
+		{name: "US without trunk", raw: "(415) 555.2671", region: "US", want: "+14155552671"},
+		{name: "GB trunk 0", raw: "020 7946 0958", region: "GB", want: "+442079460958"},
+		{name: "FR trunk 0", raw: "06 12 34 56 78", region: "FR", want: "+33612345678"},
+		{name: "ES has no trunk", raw: "612 345 678", region: "ES", want: "+34612345678"},
+		{name: "IT keeps its leading 0", raw: "06 1234 5678", region: "IT", want: "+390612345678"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.raw, tt.region)
+			if err != nil {
+				t.Fatalf("Normalize(%q, %q) error = %v", tt.raw, tt.region, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q, %q) = %q, want %q", tt.raw, tt.region, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		region string
+	}{
+		{name: "empty", raw: "  ", region: "TR"},
+		{name: "letters", raw: "0555 CALL NOW", region: "TR"},
+		{name: "national too short", raw: "0555 123", region: "TR"},
+		{name: "national too long", raw: "0555 123 45 678", region: "TR"},
+		{name: "national with unknown region", raw: "0555 123 45 67", region: "XX"},
+		{name: "international too short for its country", raw: "+90 555 123 45 6", region: "TR"},
+		{name: "country code starting with 0", raw: "+0123456789", region: "TR"},
+		{name: "more than 15 digits", raw: "+1234567890123456", region: "TR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.raw, tt.region)
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Normalize(%q, %q) = %q, %v; want ErrInvalid", tt.raw, tt.region, got, err)
+			}
+		})
+	}
+}
+
+func TestSupportedRegion(t *testing.T) {
+	for code, want := range map[string]bool{"TR": true, "us": true, "XX": false, "": false} {
+		if got := SupportedRegion(code); got != want {
+			t.Errorf("SupportedRegion(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Package sms works out how a message body is encoded on the air interface
+// and how many segments (billable parts) it will be split into.
+package sms
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	EncodingGSM7 = "GSM-7"
+	EncodingUCS2 = "UCS-2"
+)
+
+const (
+	gsm7Single = 160
+	gsm7Multi  = 153
+	ucs2Single = 70
+	ucs2Multi  = 67
+)
+
+// gsm7Basic is the GSM 03.38 default alphabet (without the escape character).
+const gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsm7Extension characters are sent as escape + char and cost two septets.
+const gsm7Extension = "\f^{}\\[~]|€"
+
+// Info describes how a message will be sent.
+type Info struct {
+	Encoding string
+	// Units is the length in the chosen encoding: septets for GSM-7,
+	// UTF-16 code units for UCS-2.
+	Units    int
+	Segments int
+}
+
+// Analyze picks GSM-7 when every character is representable in it and UCS-2
+// otherwise, and counts the resulting segments. Concatenated messages lose
+// 7 (GSM-7) or 3 (UCS-2) characters per segment to the UDH header.
+func Analyze(content string) Info {
+	if septets, ok := gsm7Length(content); ok {
+		return Info{Encoding: EncodingGSM7, Units: septets, Segments: segments(septets, gsm7Single, gsm7Multi)}
+	}
+	units := len(utf16.Encode([]rune(content)))
+	return Info{Encoding: EncodingUCS2, Units: units, Segments: segments(units, ucs2Single, ucs2Multi)}
+}
+
+func gsm7Length(content string) (int, bool) {
+	n := 0
+	for _, r := range content {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			n++
+		case strings.ContainsRune(gsm7Extension, r):
+			n += 2
+		default:
+			return 0, false
+		}
+	}
+	return n, true
+}
+
+func segments(units, single, multi int) int {
+	if units == 0 {
+		return 0
+	}
+	if units <= single {
+		return 1
+	}
+	return (units + multi - 1) / multi
+}
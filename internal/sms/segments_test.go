@@ -0,0 +1,44 @@
+package sms
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Info
+	}{
+		{name: "empty", content: "", want: Info{EncodingGSM7, 0, 0}},
+		{name: "plain ASCII", content: "hello", want: Info{EncodingGSM7, 5, 1}},
+		{name: "GSM-7 basic accents", content: "Ça va? ñ ü é", want: Info{EncodingGSM7, 12, 1}},
+
+		{name: "GSM-7 single segment limit", content: strings.Repeat("a", 160), want: Info{EncodingGSM7, 160, 1}},
+		{name: "GSM-7 one over single", content: strings.Repeat("a", 161), want: Info{EncodingGSM7, 161, 2}},
+		{name: "GSM-7 two full parts", content: strings.Repeat("a", 306), want: Info{EncodingGSM7, 306, 2}},
+		{name: "GSM-7 one over two parts", content: strings.Repeat("a", 307), want: Info{EncodingGSM7, 307, 3}},
+
+		{name: "extension chars count double", content: "[]{}", want: Info{EncodingGSM7, 8, 1}},
+		{name: "euro sign counts double", content: "10€", want: Info{EncodingGSM7, 4, 1}},
+		{name: "80 euro signs fill one segment", content: strings.Repeat("€", 80), want: Info{EncodingGSM7, 160, 1}},
+		{name: "extension char pushes over the limit", content: strings.Repeat("a", 159) + "€", want: Info{EncodingGSM7, 161, 2}},
+
+		{name: "one non-GSM char switches to UCS-2", content: "Merhaba ş", want: Info{EncodingUCS2, 9, 1}},
+		{name: "UCS-2 single segment limit", content: strings.Repeat("ş", 70), want: Info{EncodingUCS2, 70, 1}},
+		{name: "UCS-2 one over single", content: strings.Repeat("ş", 71), want: Info{EncodingUCS2, 71, 2}},
+		{name: "UCS-2 two full parts", content: strings.Repeat("ş", 134), want: Info{EncodingUCS2, 134, 2}},
+		{name: "UCS-2 one over two parts", content: strings.Repeat("ş", 135), want: Info{EncodingUCS2, 135, 3}},
+		{name: "extension chars are single units in UCS-2", content: strings.Repeat("€", 69) + "ş", want: Info{EncodingUCS2, 70, 1}},
+		{name: "emoji take two UTF-16 units", content: strings.Repeat("😀", 35), want: Info{EncodingUCS2, 70, 1}},
+		{name: "emoji over the single limit", content: strings.Repeat("😀", 36), want: Info{EncodingUCS2, 72, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Analyze(tt.content); got != tt.want {
+				t.Errorf("Analyze() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
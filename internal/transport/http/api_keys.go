@@ -0,0 +1,138 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/temo927/go-msg-dispatcher/internal/apikey"
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+type createAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// TenantID defaults to the caller's tenant; only tenant admins may pick
+	// another one.
+	TenantID string `json:"tenant_id"`
+}
+
+// CreateAPIKey returns the plaintext key once; only its hash is stored.
+func (h *Handlers) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, r, errMalformedBody)
+		return
+	}
+	var missing []string
+	if req.Name == "" {
+		missing = append(missing, "name")
+	}
+	if len(req.Scopes) == 0 {
+		missing = append(missing, "scopes")
+	}
+	if len(missing) > 0 {
+		WriteError(w, r, missingFields("name and scopes are required", missing...))
+		return
+	}
+	for _, s := range req.Scopes {
+		if !slices.Contains(domain.Scopes, s) {
+			WriteError(w, r, invalidField(CodeValidationFailed, "scopes", "unknown scope "+s))
+			return
+		}
+	}
+
+	callerTenantID, admin := callerTenant(r)
+	if req.TenantID == "" {
+		req.TenantID = callerTenantID
+	}
+	if !admin && req.TenantID != callerTenantID {
+		WriteError(w, r, forbiddenField(CodeForbidden, "tenant_id", "only tenant admins can create keys for other tenants"))
+		return
+	}
+	// A key can only hand out what it holds itself, and scopes reaching
+	// beyond one tenant stay with tenant admins.
+	if caller, ok := APIKeyFromContext(r.Context()); ok {
+		for _, s := range req.Scopes {
+			if !caller.HasScope(s) {
+				WriteError(w, r, forbiddenField(CodeInsufficientScope, "scopes", "cannot grant scope "+s+" the api key does not hold"))
+				return
+			}
+			if !admin && slices.Contains(domain.GlobalScopes, s) {
+				WriteError(w, r, forbiddenField(CodeInsufficientScope, "scopes", "only tenant admins can grant "+s))
+				return
+			}
+		}
+	}
+
+	plaintext, prefix, err := apikey.Generate()
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	key, err := h.APIKeys.CreateAPIKey(r.Context(), domain.APIKey{
+		TenantID: req.TenantID,
+		Name:     req.Name,
+		Prefix:   prefix,
+		Scopes:   req.Scopes,
+	}, apikey.Hash(plaintext))
+	if err != nil {
+		if errors.Is(err, domain.ErrTenantNotFound) {
+			WriteError(w, r, invalidField(CodeTenantNotFound, "tenant_id", fmt.Sprintf("tenant %q does not exist", req.TenantID)))
+			return
+		}
+		WriteError(w, r, err)
+		return
+	}
+
+	resp := apiKeyResponse(key)
+	resp["key"] = plaintext
+	JSONSuccess(w, http.StatusCreated, resp)
+}
+
+func (h *Handlers) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	tenantID, admin := callerTenant(r)
+	if admin {
+		tenantID = r.URL.Query().Get("tenant_id")
+	}
+
+	keys, err := h.APIKeys.ListAPIKeys(r.Context(), tenantID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	resp := make([]map[string]any, 0, len(keys))
+	for _, k := range keys {
+		resp = append(resp, apiKeyResponse(k))
+	}
+	JSONSuccess(w, http.StatusOK, map[string]any{"items": resp, "count": len(resp)})
+}
+
+func (h *Handlers) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	tenantID, admin := callerTenant(r)
+	if admin {
+		tenantID = ""
+	}
+
+	if err := h.APIKeys.RevokeAPIKey(r.Context(), tenantID, r.PathValue("id")); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	JSONSuccess(w, http.StatusOK, map[string]string{"message": "api key revoked"})
+}
+
+func apiKeyResponse(k domain.APIKey) map[string]any {
+	return map[string]any{
+		"id":           k.ID,
+		"tenant_id":    k.TenantID,
+		"name":         k.Name,
+		"prefix":       k.Prefix,
+		"scopes":       k.Scopes,
+		"created_at":   k.CreatedAt,
+		"last_used_at": k.LastUsedAt,
+		"revoked_at":   k.RevokedAt,
+	}
+}
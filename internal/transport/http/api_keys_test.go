@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+type fakeAPIKeys struct {
+	domain.APIKeysRepo
+	created []domain.APIKey
+}
+
+func (f *fakeAPIKeys) CreateAPIKey(_ context.Context, key domain.APIKey, _ string) (domain.APIKey, error) {
+	key.ID = "key-1"
+	f.created = append(f.created, key)
+	return key, nil
+}
+
+func TestCreateAPIKeyScopes(t *testing.T) {
+	tenantKeysAdmin := &domain.APIKey{TenantID: "billing", Scopes: []string{domain.ScopeKeysAdmin, domain.ScopeMessagesWrite}}
+	tenantSchedulerAdmin := &domain.APIKey{TenantID: "billing", Scopes: []string{domain.ScopeKeysAdmin, domain.ScopeSchedulerAdmin}}
+	tenantsAdmin := &domain.APIKey{TenantID: domain.DefaultTenant, Scopes: []string{domain.ScopeKeysAdmin, domain.ScopeTenantsAdmin, domain.ScopeSchedulerAdmin}}
+
+	tests := []struct {
+		name       string
+		caller     *domain.APIKey
+		body       string
+		wantStatus int
+		wantCode   ErrorCode
+	}{
+		{name: "grants a held scope", caller: tenantKeysAdmin, body: `{"name":"svc","scopes":["messages:write"]}`, wantStatus: http.StatusCreated},
+		{name: "grants keys:admin it holds", caller: tenantKeysAdmin, body: `{"name":"svc","scopes":["keys:admin"]}`, wantStatus: http.StatusCreated},
+		{name: "scope not held", caller: tenantKeysAdmin, body: `{"name":"svc","scopes":["messages:read"]}`, wantStatus: http.StatusForbidden, wantCode: CodeInsufficientScope},
+		{name: "scheduler:admin not held", caller: tenantKeysAdmin, body: `{"name":"svc","scopes":["scheduler:admin"]}`, wantStatus: http.StatusForbidden, wantCode: CodeInsufficientScope},
+		{name: "ops:admin not held", caller: tenantKeysAdmin, body: `{"name":"svc","scopes":["ops:admin"]}`, wantStatus: http.StatusForbidden, wantCode: CodeInsufficientScope},
+		{name: "tenants:admin not held", caller: tenantKeysAdmin, body: `{"name":"svc","scopes":["tenants:admin"]}`, wantStatus: http.StatusForbidden, wantCode: CodeInsufficientScope},
+		{name: "global scope held by a tenant key", caller: tenantSchedulerAdmin, body: `{"name":"svc","scopes":["scheduler:admin"]}`, wantStatus: http.StatusForbidden, wantCode: CodeInsufficientScope},
+		{name: "other tenant", caller: tenantKeysAdmin, body: `{"name":"svc","scopes":["messages:write"],"tenant_id":"shipping"}`, wantStatus: http.StatusForbidden, wantCode: CodeForbidden},
+		{name: "tenant admin grants a global scope", caller: tenantsAdmin, body: `{"name":"ops","scopes":["scheduler:admin"],"tenant_id":"billing"}`, wantStatus: http.StatusCreated},
+		{name: "tenant admin scope not held", caller: tenantsAdmin, body: `{"name":"ops","scopes":["ops:admin"]}`, wantStatus: http.StatusForbidden, wantCode: CodeInsufficientScope},
+		{name: "auth disabled", caller: nil, body: `{"name":"svc","scopes":["ops:admin"]}`, wantStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := &fakeAPIKeys{}
+			h := &Handlers{APIKeys: keys}
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/keys", strings.NewReader(tt.body))
+			if tt.caller != nil {
+				r = r.WithContext(context.WithValue(r.Context(), apiKeyCtxKey{}, *tt.caller))
+			}
+			w := httptest.NewRecorder()
+			h.CreateAPIKey(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusCreated {
+				var resp responseEnvelope
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Code != tt.wantCode {
+					t.Errorf("code = %q; want %q", resp.Code, tt.wantCode)
+				}
+				if len(keys.created) != 0 {
+					t.Errorf("refused request still created %d keys", len(keys.created))
+				}
+			}
+		})
+	}
+}
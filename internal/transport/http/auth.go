@@ -0,0 +1,130 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/apikey"
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+)
+
+type apiKeyCtxKey struct{}
+
+type AuthConfig struct {
+	// Enabled turns API key checks on. When off every route is open, as it
+	// was before keys existed.
+	Enabled bool
+	// BootstrapKey is accepted with every scope without being stored, so the
+	// first real keys can be created. Leave it empty once they exist.
+	BootstrapKey string
+}
+
+// Authenticator checks API keys sent as "Authorization: Bearer <key>" or
+// "X-API-Key: <key>" against the hashed keys in Postgres.
+type Authenticator struct {
+	keys          domain.APIKeysRepo
+	cfg           AuthConfig
+	bootstrapHash string
+}
+
+func NewAuthenticator(keys domain.APIKeysRepo, cfg AuthConfig) *Authenticator {
+	a := &Authenticator{keys: keys, cfg: cfg}
+	if cfg.BootstrapKey != "" {
+		a.bootstrapHash = apikey.Hash(cfg.BootstrapKey)
+	}
+	return a
+}
+
+// Require wraps next so it only runs for keys granted scope.
+func (a *Authenticator) Require(scope string, next http.HandlerFunc) http.HandlerFunc {
+	if !a.cfg.Enabled {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		raw := presentedKey(r)
+		if raw == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			WriteError(w, r, &APIError{Status: http.StatusUnauthorized, Code: CodeMissingAPIKey, Message: "missing api key"})
+			return
+		}
+
+		key, err := a.lookup(r.Context(), raw)
+		if err != nil {
+			if errors.Is(err, domain.ErrAPIKeyNotFound) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+				WriteError(w, r, &APIError{Status: http.StatusUnauthorized, Code: CodeInvalidAPIKey, Message: "invalid api key"})
+				return
+			}
+			WriteError(w, r, fmt.Errorf("api key lookup: %w", err))
+			return
+		}
+		if !key.HasScope(scope) {
+			WriteError(w, r, &APIError{Status: http.StatusForbidden, Code: CodeInsufficientScope, Message: "api key lacks scope " + scope})
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), apiKeyCtxKey{}, key)))
+	}
+}
+
+func (a *Authenticator) lookup(ctx context.Context, raw string) (domain.APIKey, error) {
+	hash := apikey.Hash(raw)
+	if a.bootstrapHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(a.bootstrapHash)) == 1 {
+		return domain.APIKey{ID: "bootstrap", TenantID: domain.DefaultTenant, Name: "bootstrap", Scopes: domain.Scopes}, nil
+	}
+
+	key, err := a.keys.FindAPIKeyByHash(ctx, hash)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+	// TouchAPIKey only writes once a minute anyway; skip the goroutine and
+	// the round trip while the key we just read says it is recent.
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) >= touchInterval {
+		go a.touch(key.ID)
+	}
+	return key, nil
+}
+
+// touchInterval is how often a key's last_used_at is refreshed.
+const touchInterval = time.Minute
+
+func (a *Authenticator) touch(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := a.keys.TouchAPIKey(ctx, id); err != nil {
+		log.Logger.Error("api key touch failed", "key_id", id, "err", err)
+	}
+}
+
+func presentedKey(r *http.Request) string {
+	if v := r.Header.Get("X-API-Key"); v != "" {
+		return v
+	}
+	if v, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(v)
+	}
+	return ""
+}
+
+// APIKeyFromContext returns the key that authenticated the request.
+func APIKeyFromContext(ctx context.Context) (domain.APIKey, bool) {
+	k, ok := ctx.Value(apiKeyCtxKey{}).(domain.APIKey)
+	return k, ok
+}
+
+// callerTenant returns the tenant the request acts for, and whether it may
+// reach across tenants. Without authentication everything belongs to the
+// default tenant and nothing is off limits.
+func callerTenant(r *http.Request) (tenantID string, admin bool) {
+	k, ok := APIKeyFromContext(r.Context())
+	if !ok {
+		return domain.DefaultTenant, true
+	}
+	return k.TenantID, k.HasScope(domain.ScopeTenantsAdmin)
+}
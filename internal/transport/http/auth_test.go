@@ -0,0 +1,70 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+// lookupKeys finds one key and reports every touch on touched.
+type lookupKeys struct {
+	domain.APIKeysRepo
+	key     domain.APIKey
+	touched chan string
+}
+
+func (k *lookupKeys) FindAPIKeyByHash(context.Context, string) (domain.APIKey, error) {
+	return k.key, nil
+}
+
+func (k *lookupKeys) TouchAPIKey(_ context.Context, id string) error {
+	k.touched <- id
+	return nil
+}
+
+func TestAuthenticatorTouchesStaleKeys(t *testing.T) {
+	recent := time.Now().Add(-10 * time.Second)
+	stale := time.Now().Add(-2 * touchInterval)
+	tests := []struct {
+		name       string
+		lastUsedAt *time.Time
+		wantTouch  bool
+	}{
+		{name: "never used", lastUsedAt: nil, wantTouch: true},
+		{name: "used long ago", lastUsedAt: &stale, wantTouch: true},
+		{name: "used just now", lastUsedAt: &recent, wantTouch: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := &lookupKeys{
+				key:     domain.APIKey{ID: "key-1", TenantID: "billing", Scopes: []string{domain.ScopeMessagesRead}, LastUsedAt: tt.lastUsedAt},
+				touched: make(chan string, 1),
+			}
+			auth := NewAuthenticator(keys, AuthConfig{Enabled: true})
+			h := auth.Require(domain.ScopeMessagesRead, func(w http.ResponseWriter, r *http.Request) {})
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/messages/sent", nil)
+			r.Header.Set("X-API-Key", "secret")
+			w := httptest.NewRecorder()
+			h(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d; want %d: %s", w.Code, http.StatusOK, w.Body)
+			}
+
+			select {
+			case <-keys.touched:
+				if !tt.wantTouch {
+					t.Error("touched a key used less than a minute ago")
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.wantTouch {
+					t.Error("key was not touched")
+				}
+			}
+		})
+	}
+}
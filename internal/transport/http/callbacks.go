@@ -0,0 +1,117 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+	"github.com/temo927/go-msg-dispatcher/pkg/webhooksig"
+)
+
+const (
+	deliverySignatureHeader = "X-Signature"
+	maxCallbackBody         = 64 << 10
+)
+
+type deliveryReportRequest struct {
+	ProviderMessageID string     `json:"provider_message_id"`
+	Channel           string     `json:"channel"`
+	Status            string     `json:"status"`
+	DeliveredAt       *time.Time `json:"delivered_at"`
+	ErrorCode         string     `json:"error_code"`
+}
+
+// DeliveryCallback accepts delivery receipts (DLRs) from the provider. They are
+// signed like our own webhooks (see pkg/webhooksig) with the shared callback
+// secret and sent in the X-Signature header, so a captured receipt can only be
+// replayed within webhooksig.DefaultTolerance.
+func (h *Handlers) DeliveryCallback(w http.ResponseWriter, r *http.Request) {
+	if h.cfg.DeliveryCallbackSecret == "" {
+		JSONError(w, http.StatusServiceUnavailable, "delivery callbacks are not configured")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxCallbackBody))
+	if err != nil {
+		WriteError(w, r, errMalformedBody)
+		return
+	}
+	if err := webhooksig.Verify(body, r.Header.Get(deliverySignatureHeader), []string{h.cfg.DeliveryCallbackSecret}, 0); err != nil {
+		WriteError(w, r, &APIError{Status: http.StatusUnauthorized, Code: CodeInvalidSignature, Message: "invalid signature"})
+		return
+	}
+
+	var req deliveryReportRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		WriteError(w, r, errMalformedBody)
+		return
+	}
+	var missing []string
+	if req.ProviderMessageID == "" {
+		missing = append(missing, "provider_message_id")
+	}
+	if req.DeliveredAt == nil {
+		missing = append(missing, "delivered_at")
+	}
+	if len(missing) > 0 {
+		WriteError(w, r, missingFields("provider_message_id and delivered_at are required", missing...))
+		return
+	}
+	// A receipt dated ahead would outrank every later one.
+	if req.DeliveredAt.After(time.Now().Add(webhooksig.DefaultTolerance)) {
+		WriteError(w, r, invalidField(CodeValidationFailed, "delivered_at", "delivered_at is in the future"))
+		return
+	}
+
+	switch req.Channel {
+	case "", domain.ChannelSMS, domain.ChannelEmail, domain.ChannelPush:
+	default:
+		WriteError(w, r, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    CodeInvalidRequest,
+			Message: "channel must be sms, email or push",
+			Details: []FieldError{{Field: "channel", Message: "must be sms, email or push"}},
+		})
+		return
+	}
+
+	report := domain.DeliveryReport{
+		ProviderMessageID: req.ProviderMessageID,
+		Channel:           req.Channel,
+		ErrorCode:         req.ErrorCode,
+		At:                req.DeliveredAt.UTC(),
+	}
+	switch req.Status {
+	case "delivered":
+		report.Delivered = true
+	case "undelivered":
+	default:
+		WriteError(w, r, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    CodeInvalidRequest,
+			Message: "status must be delivered or undelivered",
+			Details: []FieldError{{Field: "status", Message: "must be delivered or undelivered"}},
+		})
+		return
+	}
+	msg, err := h.Repo.ApplyDeliveryReport(r.Context(), report)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	log.Logger.InfoContext(r.Context(), "delivery report applied",
+		"msg_id", msg.ID,
+		"provider_message_id", req.ProviderMessageID,
+		"status", msg.Status,
+	)
+	JSONSuccess(w, http.StatusOK, map[string]any{
+		"id":                  msg.ID,
+		"status":              msg.Status,
+		"delivered_at":        msg.DeliveredAt,
+		"delivery_error_code": msg.DeliveryErrorCode,
+	})
+}
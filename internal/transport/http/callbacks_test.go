@@ -0,0 +1,137 @@
+package http
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/pkg/webhooksig"
+)
+
+type fakeMessages struct {
+	domain.MessagesRepo
+	sentAt  time.Time
+	applied []domain.DeliveryReport
+}
+
+func (f *fakeMessages) ApplyDeliveryReport(_ context.Context, report domain.DeliveryReport) (domain.Message, error) {
+	if report.At.Before(f.sentAt) {
+		return domain.Message{}, domain.ErrReportBeforeSend
+	}
+	f.applied = append(f.applied, report)
+	return domain.Message{ID: "m-1", Status: "delivered", DeliveredAt: &report.At}, nil
+}
+
+func TestDeliveryCallback(t *testing.T) {
+	const secret = "dlr-secret"
+	now := time.Now()
+	sentAt := now.Add(-time.Hour)
+	report := func(deliveredAt string) string {
+		body := `{"provider_message_id":"p-1","status":"delivered"`
+		if deliveredAt != "" {
+			body += `,"delivered_at":"` + deliveredAt + `"`
+		}
+		return body + "}"
+	}
+	bodyOnly := func(body string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		signature  func(body string) string
+		wantStatus int
+		wantCode   ErrorCode
+	}{
+		{
+			name:       "signed receipt",
+			body:       report(now.Add(-time.Minute).Format(time.RFC3339)),
+			signature:  func(b string) string { return webhooksig.Sign([]byte(b), now, secret) },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "body-only signature",
+			body:       report(now.Add(-time.Minute).Format(time.RFC3339)),
+			signature:  bodyOnly,
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeInvalidSignature,
+		},
+		{
+			name:       "replayed after the tolerance",
+			body:       report(now.Add(-time.Minute).Format(time.RFC3339)),
+			signature:  func(b string) string { return webhooksig.Sign([]byte(b), now.Add(-time.Hour), secret) },
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeInvalidSignature,
+		},
+		{
+			name:       "wrong secret",
+			body:       report(now.Add(-time.Minute).Format(time.RFC3339)),
+			signature:  func(b string) string { return webhooksig.Sign([]byte(b), now, "other") },
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeInvalidSignature,
+		},
+		{
+			name:       "missing delivered_at",
+			body:       report(""),
+			signature:  func(b string) string { return webhooksig.Sign([]byte(b), now, secret) },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   CodeInvalidRequest,
+		},
+		{
+			name:       "delivered_at in the future",
+			body:       report(now.Add(time.Hour).Format(time.RFC3339)),
+			signature:  func(b string) string { return webhooksig.Sign([]byte(b), now, secret) },
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   CodeValidationFailed,
+		},
+		{
+			name:       "delivered_at before the send",
+			body:       report(sentAt.Add(-time.Minute).Format(time.RFC3339)),
+			signature:  func(b string) string { return webhooksig.Sign([]byte(b), now, secret) },
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   CodeValidationFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeMessages{sentAt: sentAt}
+			h := &Handlers{Repo: repo, cfg: HandlersConfig{DeliveryCallbackSecret: secret}}
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/callbacks/delivery", strings.NewReader(tt.body))
+			r.Header.Set(deliverySignatureHeader, tt.signature(tt.body))
+			w := httptest.NewRecorder()
+			h.DeliveryCallback(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if len(repo.applied) != 1 {
+					t.Fatalf("applied %d reports; want 1", len(repo.applied))
+				}
+				return
+			}
+			var resp responseEnvelope
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != tt.wantCode {
+				t.Errorf("code = %q; want %q", resp.Code, tt.wantCode)
+			}
+			if len(repo.applied) != 0 {
+				t.Errorf("refused receipt still applied %d reports", len(repo.applied))
+			}
+		})
+	}
+}
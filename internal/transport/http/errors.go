@@ -0,0 +1,183 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/temo927/go-msg-dispatcher/internal/app"
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+)
+
+// ErrorCode is the stable, machine-readable reason of an error response.
+// Clients branch on it; the message next to it may change at any time.
+type ErrorCode string
+
+const (
+	// Generic codes, derived from the status when nothing more specific applies.
+	CodeInvalidRequest   ErrorCode = "invalid_request"
+	CodeValidationFailed ErrorCode = "validation_failed"
+	CodeUnauthorized     ErrorCode = "unauthorized"
+	CodeForbidden        ErrorCode = "forbidden"
+	CodeNotFound         ErrorCode = "not_found"
+	CodeMethodNotAllowed ErrorCode = "method_not_allowed"
+	CodeConflict         ErrorCode = "conflict"
+	CodeRateLimited      ErrorCode = "rate_limited"
+	CodeInternal         ErrorCode = "internal_error"
+	CodeUnavailable      ErrorCode = "unavailable"
+
+	CodeMalformedBody     ErrorCode = "malformed_body"
+	CodeBodyTooLarge      ErrorCode = "body_too_large"
+	CodeRouteNotFound     ErrorCode = "route_not_found"
+	CodeMissingAPIKey     ErrorCode = "missing_api_key"
+	CodeInvalidAPIKey     ErrorCode = "invalid_api_key"
+	CodeInsufficientScope ErrorCode = "insufficient_scope"
+	CodeInvalidSignature  ErrorCode = "invalid_signature"
+	CodeNotReady          ErrorCode = "not_ready"
+
+	CodeMessageNotFound     ErrorCode = "message_not_found"
+	CodeDuplicateMessage    ErrorCode = "duplicate_message"
+	CodeQuotaExceeded       ErrorCode = "quota_exceeded"
+	CodeUnsupportedChannel  ErrorCode = "unsupported_channel"
+	CodeInvalidRecipient    ErrorCode = "invalid_recipient"
+	CodeContentTooLong      ErrorCode = "content_too_long"
+	CodeTemplateNotFound    ErrorCode = "template_not_found"
+	CodeTemplateExists      ErrorCode = "template_exists"
+	CodeTemplateRender      ErrorCode = "template_render_failed"
+	CodeSuppressionNotFound ErrorCode = "suppression_not_found"
+	CodeAPIKeyNotFound      ErrorCode = "api_key_not_found"
+	CodeTenantNotFound      ErrorCode = "tenant_not_found"
+	CodeTenantExists        ErrorCode = "tenant_exists"
+	CodeSchedulerRunning    ErrorCode = "scheduler_already_running"
+	CodeSchedulerNotRunning ErrorCode = "scheduler_not_running"
+)
+
+// FieldError explains why one request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// APIError is an error meant for the client: everything in it is safe to
+// return as is.
+type APIError struct {
+	Status  int
+	Code    ErrorCode
+	Message string
+	Details []FieldError
+}
+
+func (e *APIError) Error() string { return e.Message }
+
+// errMalformedBody is returned for request bodies that are not valid JSON.
+var errMalformedBody = &APIError{Status: http.StatusBadRequest, Code: CodeMalformedBody, Message: "invalid request body"}
+
+// missingFields reports required fields the request left empty, keeping the
+// handler's summary as the message.
+func missingFields(message string, fields ...string) *APIError {
+	details := make([]FieldError, 0, len(fields))
+	for _, f := range fields {
+		details = append(details, FieldError{Field: f, Message: "is required"})
+	}
+	return &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    CodeInvalidRequest,
+		Message: message,
+		Details: details,
+	}
+}
+
+// invalidField rejects a single field that is present but unacceptable.
+func invalidField(code ErrorCode, field, message string) *APIError {
+	return &APIError{
+		Status:  http.StatusUnprocessableEntity,
+		Code:    code,
+		Message: message,
+		Details: []FieldError{{Field: field, Message: message}},
+	}
+}
+
+// forbiddenField refuses a field the caller is not allowed to set.
+func forbiddenField(code ErrorCode, field, message string) *APIError {
+	return &APIError{
+		Status:  http.StatusForbidden,
+		Code:    code,
+		Message: message,
+		Details: []FieldError{{Field: field, Message: message}},
+	}
+}
+
+// knownErrors maps the domain and app errors clients can act on to their
+// response. The message is the sentinel's own text, never the wrapped error,
+// which may carry internal details.
+var knownErrors = []struct {
+	err    error
+	status int
+	code   ErrorCode
+}{
+	{domain.ErrMessageNotFound, http.StatusNotFound, CodeMessageNotFound},
+	{domain.ErrAmbiguousReport, http.StatusConflict, CodeConflict},
+	{domain.ErrReportBeforeSend, http.StatusUnprocessableEntity, CodeValidationFailed},
+	{domain.ErrTemplateNotFound, http.StatusNotFound, CodeTemplateNotFound},
+	{domain.ErrTemplateExists, http.StatusConflict, CodeTemplateExists},
+	{domain.ErrSuppressionNotFound, http.StatusNotFound, CodeSuppressionNotFound},
+	{domain.ErrAPIKeyNotFound, http.StatusNotFound, CodeAPIKeyNotFound},
+	{domain.ErrTenantNotFound, http.StatusNotFound, CodeTenantNotFound},
+	{domain.ErrTenantExists, http.StatusConflict, CodeTenantExists},
+	{app.ErrAlreadyRunning, http.StatusConflict, CodeSchedulerRunning},
+	{app.ErrNotRunning, http.StatusConflict, CodeSchedulerNotRunning},
+	{app.ErrUnsupportedChannel, http.StatusUnprocessableEntity, CodeUnsupportedChannel},
+}
+
+// WriteError answers with err mapped to its status and code. Errors that
+// are neither an *APIError nor a known domain error are logged and reported
+// as a bare internal_error, so database and provider errors never reach
+// clients.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		writeErrorEnvelope(w, apiErr.Status, apiErr.Code, apiErr.Message, apiErr.Details)
+		return
+	}
+	for _, k := range knownErrors {
+		if errors.Is(err, k.err) {
+			writeErrorEnvelope(w, k.status, k.code, k.err.Error(), nil)
+			return
+		}
+	}
+
+	log.Logger.ErrorContext(r.Context(), "request failed",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"err", err,
+	)
+	writeErrorEnvelope(w, http.StatusInternalServerError, CodeInternal, "internal server error", nil)
+}
+
+// codeForStatus is the generic code of responses given without one.
+func codeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeValidationFailed
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeInvalidRequest
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/events"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+)
+
+// sseHeartbeat keeps idle streams from being cut by proxies.
+const sseHeartbeat = 15 * time.Second
+
+// StreamEvents streams message lifecycle events of the caller's tenant as
+// Server-Sent Events, optionally narrowed by ?status= (event types, comma
+// separated), ?recipient= and ?batch_id=. Tenant admins see every tenant, or
+// the one given in ?tenant_id. Only events from after the connection opened
+// are sent; clients catch up on earlier state with the list endpoints.
+func (h *Handlers) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	if h.Events == nil {
+		JSONError(w, http.StatusServiceUnavailable, "event streaming is not configured")
+		return
+	}
+
+	q := r.URL.Query()
+	tenantID, admin := callerTenant(r)
+	if admin {
+		tenantID = q.Get("tenant_id")
+	}
+	filter := events.Filter{
+		TenantID:  tenantID,
+		Recipient: q.Get("recipient"),
+		BatchID:   q.Get("batch_id"),
+	}
+	if s := q.Get("status"); s != "" {
+		for _, typ := range strings.Split(s, ",") {
+			typ = strings.TrimSpace(typ)
+			if !slices.Contains(domain.EventTypes, typ) {
+				WriteError(w, r, invalidField(CodeValidationFailed, "status",
+					fmt.Sprintf("unknown event type %q (%s)", typ, strings.Join(domain.EventTypes, ", "))))
+				return
+			}
+			filter.Types = append(filter.Types, typ)
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	// The server's WriteTimeout is meant for ordinary requests.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		WriteError(w, r, fmt.Errorf("event stream: %w", err))
+		return
+	}
+
+	sub := h.Events.Subscribe(filter)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case ev, ok := <-sub.C:
+			if !ok {
+				if sub.Lagged() {
+					log.Logger.WarnContext(r.Context(), "event stream client fell behind, disconnecting")
+					fmt.Fprint(w, "event: lagged\ndata: {}\n\n")
+					_ = rc.Flush()
+				}
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -1,36 +1,131 @@
 package http
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/mail"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/temo927/go-msg-dispatcher/internal/app"
 	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/events"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/metrics"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/tracing"
+	"github.com/temo927/go-msg-dispatcher/internal/msgtemplate"
+	"github.com/temo927/go-msg-dispatcher/internal/phone"
+	"github.com/temo927/go-msg-dispatcher/internal/sms"
 )
 
 type createMessageRequest struct {
-	ToPhone string `json:"to_phone"`
-	Content string `json:"content"`
+	Channel   string `json:"channel"`
+	Recipient string `json:"recipient"`
+	// ToPhone is the pre-channel name of Recipient, still accepted for sms.
+	ToPhone string  `json:"to_phone"`
+	Subject *string `json:"subject"`
+	Content string  `json:"content"`
+
+	// TemplateID renders Content from a stored template instead.
+	TemplateID      string            `json:"template_id"`
+	TemplateVersion int               `json:"template_version"`
+	Locale          string            `json:"locale"`
+	Variables       map[string]string `json:"variables"`
+
+	// BatchID groups messages so their events can be followed together.
+	BatchID string `json:"batch_id"`
+	// CallbackURL receives a signed POST once the message is sent,
+	// suppressed or fails for good.
+	CallbackURL string `json:"callback_url"`
 }
 
 type Handlers struct {
-	Scheduler *app.Scheduler
-	Repo      domain.MessagesRepo
+	Scheduler    *app.Scheduler
+	Repo         domain.MessagesRepo
+	Templates    domain.TemplatesRepo
+	Suppressions domain.SuppressionsRepo
+	Dedup        domain.Deduplicator
+	APIKeys      domain.APIKeysRepo
+	Tenants      domain.TenantsRepo
+	Callbacks    domain.StatusCallbacksRepo
+	Events       *events.Bus
+	cfg          HandlersConfig
+}
+
+type HandlersConfig struct {
+	// DeliveryCallbackSecret is the shared secret providers sign delivery
+	// receipts with. The callback endpoint is disabled while it is empty.
+	DeliveryCallbackSecret string
+	// Channels lists the channels a global provider is registered for;
+	// messages for any other channel are rejected at create time unless the
+	// tenant configured its own provider for it.
+	Channels []string
+	// DefaultPhoneRegion is the ISO region national sms numbers are
+	// interpreted in, e.g. "TR" turns 0555 123 45 67 into +905551234567.
+	DefaultPhoneRegion string
+	// MaxMessageChars caps the content length in characters.
+	MaxMessageChars int
+	// DedupWindow enables duplicate detection: an identical (recipient,
+	// content) pair enqueued again within the window is rejected with 409
+	// (DedupMode "reject") or answered with the original message
+	// (DedupMode "collapse"). Zero disables it.
+	DedupWindow time.Duration
+	DedupMode   string
+	// StatusCallbacks accepts callback_url on create, from tenants with a
+	// callback signing secret to sign them with.
+	StatusCallbacks bool
+	// StatusCallbackAllowedHosts, if set, limits callback_url to these hosts
+	// and their subdomains.
+	StatusCallbackAllowedHosts []string
 }
 
-func NewHandlers(scheduler *app.Scheduler, repo domain.MessagesRepo) *Handlers {
-	return &Handlers{Scheduler: scheduler, Repo: repo}
+const (
+	DedupReject   = "reject"
+	DedupCollapse = "collapse"
+)
+
+// maxBatchIDLen matches the messages.batch_id column.
+const maxBatchIDLen = 64
+
+// maxSubjectChars matches the messages.subject column.
+const maxSubjectChars = 255
+
+func NewHandlers(
+	scheduler *app.Scheduler,
+	repo domain.MessagesRepo,
+	templates domain.TemplatesRepo,
+	suppressions domain.SuppressionsRepo,
+	dedup domain.Deduplicator,
+	apiKeys domain.APIKeysRepo,
+	tenants domain.TenantsRepo,
+	callbacks domain.StatusCallbacksRepo,
+	bus *events.Bus,
+	cfg HandlersConfig,
+) *Handlers {
+	return &Handlers{
+		Scheduler:    scheduler,
+		Repo:         repo,
+		Templates:    templates,
+		Suppressions: suppressions,
+		Dedup:        dedup,
+		APIKeys:      apiKeys,
+		Tenants:      tenants,
+		Callbacks:    callbacks,
+		Events:       bus,
+		cfg:          cfg,
+	}
 }
 
 func (h *Handlers) StartScheduler(w http.ResponseWriter, r *http.Request) {
 	if err := h.Scheduler.Start(r.Context()); err != nil {
-		switch err {
-		case app.ErrAlreadyRunning:
-			JSONError(w, http.StatusConflict, err.Error())
-		default:
-			JSONError(w, http.StatusInternalServerError, err.Error())
-		}
+		WriteError(w, r, err)
 		return
 	}
 	JSONSuccess(w, http.StatusOK, map[string]string{"message": "scheduler started"})
@@ -38,72 +133,403 @@ func (h *Handlers) StartScheduler(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handlers) StopScheduler(w http.ResponseWriter, r *http.Request) {
 	if err := h.Scheduler.Stop(); err != nil {
-		switch err {
-		case app.ErrNotRunning:
-			JSONError(w, http.StatusConflict, err.Error())
-		default:
-			JSONError(w, http.StatusInternalServerError, err.Error())
-		}
+		WriteError(w, r, err)
 		return
 	}
 	JSONSuccess(w, http.StatusOK, map[string]string{"message": "scheduler stopped"})
 }
 
+// ListSent lists the caller's tenant. Tenant admins see every tenant, or the
+// one given in ?tenant_id.
 func (h *Handlers) ListSent(w http.ResponseWriter, r *http.Request) {
-	limit := 50
-	offset := 0
+	limit, offset := pagination(r, 50)
 
-	q := r.URL.Query()
-	if v := q.Get("limit"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			limit = n
-		}
+	tenantID, admin := callerTenant(r)
+	if admin {
+		tenantID = r.URL.Query().Get("tenant_id")
 	}
-	if v := q.Get("offset"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
-			offset = n
-		}
+
+	msgs, err := h.Repo.ListSent(r.Context(), tenantID, limit, offset)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	resp := make([]map[string]any, 0, len(msgs))
+	for _, m := range msgs {
+		resp = append(resp, messageItem(m))
+	}
+	JSONSuccess(w, http.StatusOK, map[string]any{"items": resp, "count": len(resp)})
+}
+
+// ListByRequestID lists the messages created by the request whose
+// X-Request-ID is in the path, e.g. one quoted from an error report.
+func (h *Handlers) ListByRequestID(w http.ResponseWriter, r *http.Request) {
+	tenantID, admin := callerTenant(r)
+	if admin {
+		tenantID = r.URL.Query().Get("tenant_id")
 	}
 
-	msgs, err := h.Repo.ListSent(r.Context(), limit, offset)
+	msgs, err := h.Repo.ListByRequestID(r.Context(), tenantID, r.PathValue("request_id"))
 	if err != nil {
-		JSONError(w, http.StatusInternalServerError, err.Error())
+		WriteError(w, r, err)
 		return
 	}
 
 	resp := make([]map[string]any, 0, len(msgs))
 	for _, m := range msgs {
-		resp = append(resp, map[string]any{
-			"id":                  m.ID,
-			"to_phone":            m.ToPhone,
-			"content":             m.Content,
-			"provider_message_id": m.ProviderMessageID,
-			"sent_at":             m.SentAt,
-		})
+		resp = append(resp, messageDetail(m))
 	}
 	JSONSuccess(w, http.StatusOK, map[string]any{"items": resp, "count": len(resp)})
 }
 
+// GetMessage shows one message of the caller's tenant, whatever its status.
+// Tenant admins can look up messages of every tenant.
+func (h *Handlers) GetMessage(w http.ResponseWriter, r *http.Request) {
+	tenantID, admin := callerTenant(r)
+	if admin {
+		tenantID = ""
+	}
+
+	m, err := h.Repo.GetMessage(r.Context(), tenantID, r.PathValue("id"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	JSONSuccess(w, http.StatusOK, messageDetail(m))
+}
+
+func messageItem(m domain.Message) map[string]any {
+	item := map[string]any{
+		"id":                  m.ID,
+		"tenant_id":           m.TenantID,
+		"channel":             m.Channel,
+		"recipient":           m.Recipient,
+		"subject":             m.Subject,
+		"content":             m.Content,
+		"status":              m.Status,
+		"provider_message_id": m.ProviderMessageID,
+		"sent_at":             m.SentAt,
+		"delivered_at":        m.DeliveredAt,
+		"delivery_error_code": m.DeliveryErrorCode,
+		"request_id":          m.RequestID,
+		"batch_id":            m.BatchID,
+		"callback_url":        m.CallbackURL,
+	}
+	if m.Channel == domain.ChannelSMS {
+		item["to_phone"] = m.Recipient
+	}
+	return item
+}
+
+// messageDetail adds what only matters while a message is still in flight.
+func messageDetail(m domain.Message) map[string]any {
+	item := messageItem(m)
+	item["created_at"] = m.CreatedAt
+	item["retry_count"] = m.RetryCount
+	item["last_error"] = m.LastError
+	item["last_error_code"] = m.LastErrorCode
+	item["encoding"] = m.Encoding
+	item["segments"] = m.Segments
+	item["template_id"] = m.TemplateID
+	item["template_version"] = m.TemplateVersion
+	return item
+}
+
 func (h *Handlers) CreateMessage(w http.ResponseWriter, r *http.Request) {
 	var req createMessageRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		JSONError(w, http.StatusBadRequest, "invalid request body")
+		WriteError(w, r, errMalformedBody)
+		return
+	}
+	if req.Channel == "" {
+		req.Channel = domain.ChannelSMS
+	}
+	if req.Recipient == "" && req.Channel == domain.ChannelSMS {
+		req.Recipient = req.ToPhone
+	}
+	if req.TemplateID != "" && req.Content != "" {
+		WriteError(w, r, &APIError{
+			Status:  http.StatusBadRequest,
+			Code:    CodeInvalidRequest,
+			Message: "content and template_id are mutually exclusive",
+			Details: []FieldError{
+				{Field: "content", Message: "must be empty when template_id is set"},
+				{Field: "template_id", Message: "must be empty when content is set"},
+			},
+		})
+		return
+	}
+	var missing []string
+	if req.Recipient == "" {
+		missing = append(missing, "recipient")
+	}
+	if req.Content == "" && req.TemplateID == "" {
+		missing = append(missing, "content")
+	}
+	if len(missing) > 0 {
+		WriteError(w, r, missingFields("recipient (or to_phone) and content (or template_id) are required", missing...))
+		return
+	}
+	if req.Subject != nil {
+		if req.Channel != domain.ChannelEmail {
+			WriteError(w, r, invalidField(CodeValidationFailed, "subject", "subject is only supported on the email channel"))
+			return
+		}
+		if utf8.RuneCountInString(*req.Subject) > maxSubjectChars {
+			WriteError(w, r, invalidField(CodeValidationFailed, "subject", fmt.Sprintf("subject exceeds %d characters", maxSubjectChars)))
+			return
+		}
+	}
+	if len(req.BatchID) > maxBatchIDLen {
+		WriteError(w, r, invalidField(CodeValidationFailed, "batch_id", fmt.Sprintf("batch_id exceeds %d characters", maxBatchIDLen)))
+		return
+	}
+	if req.CallbackURL != "" {
+		if !h.cfg.StatusCallbacks {
+			WriteError(w, r, invalidField(CodeValidationFailed, "callback_url", "status callbacks are not enabled on this server"))
+			return
+		}
+		if apiErr := validateCallbackURL(req.CallbackURL, h.cfg.StatusCallbackAllowedHosts); apiErr != nil {
+			WriteError(w, r, apiErr)
+			return
+		}
+	}
+
+	tenantID, _ := callerTenant(r)
+	var tmpl *domain.Template
+	if req.TemplateID != "" {
+		t, err := h.Templates.GetTemplate(r.Context(), tenantID, req.TemplateID, req.TemplateVersion)
+		if err != nil {
+			if errors.Is(err, domain.ErrTemplateNotFound) {
+				// The route exists; it's a field of the request that is wrong.
+				WriteError(w, r, invalidField(CodeTemplateNotFound, "template_id", domain.ErrTemplateNotFound.Error()))
+				return
+			}
+			WriteError(w, r, err)
+			return
+		}
+		_, body, ok := msgtemplate.PickLocale(t.Bodies, req.Locale, t.DefaultLocale)
+		if !ok {
+			WriteError(w, r, invalidField(CodeTemplateRender, "locale", fmt.Sprintf("template version %d has no body for locale %q or its default %q", t.Version, req.Locale, t.DefaultLocale)))
+			return
+		}
+		content, err := msgtemplate.Render(body, req.Variables)
+		if err != nil {
+			WriteError(w, r, invalidField(CodeTemplateRender, "variables", err.Error()))
+			return
+		}
+		if strings.TrimSpace(content) == "" {
+			WriteError(w, r, invalidField(CodeTemplateRender, "variables", "rendered template is empty"))
+			return
+		}
+		req.Content = content
+		tmpl = &t
+	}
+	if h.cfg.MaxMessageChars > 0 && utf8.RuneCountInString(req.Content) > h.cfg.MaxMessageChars {
+		WriteError(w, r, invalidField(CodeContentTooLong, "content", fmt.Sprintf("content exceeds %d characters", h.cfg.MaxMessageChars)))
 		return
 	}
-	if req.ToPhone == "" || req.Content == "" {
-		JSONError(w, http.StatusBadRequest, "to_phone and content are required")
+
+	tenant, err := h.Tenants.GetTenant(r.Context(), tenantID)
+	if err != nil {
+		if errors.Is(err, domain.ErrTenantNotFound) {
+			WriteError(w, r, &APIError{Status: http.StatusForbidden, Code: CodeTenantNotFound, Message: fmt.Sprintf("tenant %q does not exist", tenantID)})
+			return
+		}
+		WriteError(w, r, err)
+		return
+	}
+	if req.CallbackURL != "" && len(tenant.CallbackSigningSecrets) == 0 {
+		WriteError(w, r, invalidField(CodeValidationFailed, "callback_url", fmt.Sprintf("tenant %q has no callback signing secret", tenant.ID)))
+		return
+	}
+	if _, own := tenant.Providers[req.Channel]; !own && !slices.Contains(h.cfg.Channels, req.Channel) {
+		WriteError(w, r, invalidField(CodeUnsupportedChannel, "channel", fmt.Sprintf("unsupported channel %q", req.Channel)))
 		return
 	}
+	recipient, err := h.normalizeRecipient(req.Channel, req.Recipient)
+	if err != nil {
+		WriteError(w, r, invalidField(CodeInvalidRecipient, "recipient", err.Error()))
+		return
+	}
+	req.Recipient = recipient
+
+	msg := domain.Message{
+		TenantID:  tenant.ID,
+		Channel:   req.Channel,
+		Recipient: req.Recipient,
+		Subject:   req.Subject,
+		Content:   req.Content,
+	}
+	if tp := tracing.TraceParent(r.Context()); tp != "" {
+		msg.TraceParent = &tp
+	}
+	if id := log.RequestIDFromContext(r.Context()); id != "" {
+		msg.RequestID = &id
+	}
+	if req.BatchID != "" {
+		msg.BatchID = &req.BatchID
+	}
+	if req.CallbackURL != "" {
+		msg.CallbackURL = &req.CallbackURL
+	}
+	if tmpl != nil {
+		msg.TemplateID = &tmpl.ID
+		msg.TemplateVersion = &tmpl.Version
+	}
+	if req.Channel == domain.ChannelSMS {
+		info := sms.Analyze(req.Content)
+		msg.Encoding = &info.Encoding
+		msg.Segments = &info.Segments
+	}
+
+	suppressed, err := h.Suppressions.IsSuppressed(r.Context(), msg.TenantID, msg.Channel, msg.Recipient)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if suppressed {
+		// Kept for auditing, but it will never be claimed for sending.
+		msg.Status = "suppressed"
+	}
 
-	msg, err := h.Repo.Create(r.Context(), req.ToPhone, req.Content)
+	var dedupKey string
+	if h.cfg.DedupWindow > 0 {
+		dedupKey = dedupKeyFor(msg)
+		existingID, claimed, err := h.Dedup.ClaimDedup(r.Context(), dedupKey, h.cfg.DedupWindow)
+		switch {
+		case err != nil:
+			// Duplicate detection is a safety net; losing it while Redis is
+			// down beats refusing every message.
+			log.Logger.WarnContext(r.Context(), "dedup check failed, creating without it", "err", err)
+			dedupKey = ""
+		case !claimed && existingID == "":
+			// The first copy is still being created; its id is only known
+			// once that finishes.
+			w.Header().Set("Retry-After", "1")
+			WriteError(w, r, &APIError{
+				Status:  http.StatusConflict,
+				Code:    CodeDuplicateMessage,
+				Message: "an identical message is still being created; retry to get its id",
+			})
+			return
+		case !claimed:
+			log.Logger.WarnContext(r.Context(), "duplicate message",
+				"channel", msg.Channel,
+				"duplicate_of", existingID,
+				"mode", h.cfg.DedupMode,
+			)
+			if h.cfg.DedupMode == DedupCollapse {
+				JSONSuccess(w, http.StatusOK, map[string]any{
+					"id":        existingID,
+					"duplicate": true,
+				})
+				return
+			}
+			WriteError(w, r, &APIError{
+				Status:  http.StatusConflict,
+				Code:    CodeDuplicateMessage,
+				Message: fmt.Sprintf("duplicate of message %q enqueued within the last %s", existingID, h.cfg.DedupWindow),
+			})
+			return
+		}
+	}
+
+	// The quota is taken in the same transaction as the insert, so
+	// concurrent requests can't get past it.
+	if tenant.DailyQuota != nil {
+		msg, err = h.Repo.CreateWithinQuota(r.Context(), msg, *tenant.DailyQuota)
+	} else {
+		msg, err = h.Repo.Create(r.Context(), msg)
+	}
 	if err != nil {
-		JSONError(w, http.StatusInternalServerError, err.Error())
+		if dedupKey != "" {
+			_ = h.Dedup.ReleaseDedup(r.Context(), dedupKey)
+		}
+		if errors.Is(err, domain.ErrQuotaExceeded) {
+			dayStart := time.Now().UTC().Truncate(24 * time.Hour)
+			retryAfter := time.Until(dayStart.Add(24 * time.Hour))
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			WriteError(w, r, &APIError{
+				Status:  http.StatusTooManyRequests,
+				Code:    CodeQuotaExceeded,
+				Message: fmt.Sprintf("tenant %q reached its daily quota of %d messages", tenant.ID, *tenant.DailyQuota),
+			})
+			return
+		}
+		WriteError(w, r, err)
 		return
 	}
+	if dedupKey != "" {
+		if err := h.Dedup.CompleteDedup(r.Context(), dedupKey, msg.ID); err != nil {
+			// A key left pending would turn every copy away with 409 until
+			// it expires; without one, copies are simply let through.
+			log.Logger.ErrorContext(r.Context(), "dedup complete failed", "msg_id", msg.ID, "err", err)
+			if err := h.Dedup.ReleaseDedup(r.Context(), dedupKey); err != nil {
+				log.Logger.ErrorContext(r.Context(), "dedup release failed", "msg_id", msg.ID, "err", err)
+			}
+		}
+	}
+	metrics.MessagesCreated.WithLabelValues(msg.Channel, msg.Status).Inc()
+	if suppressed {
+		metrics.MessagesSuppressed.WithLabelValues(msg.Channel).Inc()
+	}
 
 	JSONSuccess(w, http.StatusCreated, map[string]any{
-		"id":      msg.ID,
-		"status":  msg.Status,
-		"created": msg.CreatedAt,
+		"id":        msg.ID,
+		"tenant_id": msg.TenantID,
+		"channel":   msg.Channel,
+		"status":    msg.Status,
+		"encoding":  msg.Encoding,
+		"segments":  msg.Segments,
+		"created":   msg.CreatedAt,
+
+		"request_id":   msg.RequestID,
+		"batch_id":     msg.BatchID,
+		"callback_url": msg.CallbackURL,
+
+		"template_id":      msg.TemplateID,
+		"template_version": msg.TemplateVersion,
 	})
 }
+
+// dedupKeyFor hashes what makes two messages duplicates of each other.
+// Tenants never collapse into each other's messages.
+func dedupKeyFor(msg domain.Message) string {
+	sum := sha256.Sum256([]byte(msg.TenantID + "\x00" + msg.Channel + "\x00" + msg.Recipient + "\x00" + msg.Content))
+	return "dedup:" + hex.EncodeToString(sum[:])
+}
+
+// normalizeRecipient validates recipient for channel and returns the form it
+// is stored (and suppressed) under.
+func (h *Handlers) normalizeRecipient(channel, recipient string) (string, error) {
+	switch channel {
+	case domain.ChannelSMS:
+		return phone.Normalize(recipient, h.cfg.DefaultPhoneRegion)
+	case domain.ChannelEmail:
+		addr, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return "", errors.New("recipient is not a valid email address")
+		}
+		return strings.ToLower(addr.Address), nil
+	}
+	return recipient, nil
+}
+
+func pagination(r *http.Request, defaultLimit int) (limit, offset int) {
+	limit = defaultLimit
+
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			offset = n
+		}
+	}
+	return limit, offset
+}
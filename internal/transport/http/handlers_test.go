@@ -0,0 +1,173 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/temo927/go-msg-dispatcher/internal/app"
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+// idleMessages is a queue with nothing to send.
+type idleMessages struct {
+	domain.MessagesRepo
+}
+
+func (idleMessages) ClaimNextBatch(context.Context, int) ([]domain.Message, error) {
+	return nil, nil
+}
+
+func TestSchedulerStartStop(t *testing.T) {
+	scheduler := app.NewScheduler(idleMessages{}, nil, time.Hour, 10)
+	h := &Handlers{Scheduler: scheduler}
+	t.Cleanup(func() { _ = scheduler.Stop() })
+
+	steps := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantCode   ErrorCode
+	}{
+		{name: "start", handler: h.StartScheduler, wantStatus: http.StatusOK},
+		{name: "start again", handler: h.StartScheduler, wantStatus: http.StatusConflict, wantCode: CodeSchedulerRunning},
+		{name: "stop", handler: h.StopScheduler, wantStatus: http.StatusOK},
+		{name: "stop again", handler: h.StopScheduler, wantStatus: http.StatusConflict, wantCode: CodeSchedulerNotRunning},
+		{name: "restart", handler: h.StartScheduler, wantStatus: http.StatusOK},
+	}
+	for _, step := range steps {
+		w := httptest.NewRecorder()
+		step.handler(w, httptest.NewRequest(http.MethodPost, "/api/v1/scheduler", nil))
+		if w.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d; want %d: %s", step.name, w.Code, step.wantStatus, w.Body)
+		}
+		if step.wantCode == "" {
+			continue
+		}
+		var resp responseEnvelope
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != step.wantCode {
+			t.Errorf("%s: code = %q; want %q", step.name, resp.Code, step.wantCode)
+		}
+	}
+}
+
+func TestCreateMessageRejectsSubject(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "sms", body: `{"channel":"sms","recipient":"+905551234567","content":"hi","subject":"Hello"}`},
+		{name: "push", body: `{"channel":"push","recipient":"device-token","content":"hi","subject":"Hello"}`},
+		{name: "too long", body: `{"channel":"email","recipient":"a@example.com","content":"hi","subject":"` + strings.Repeat("ş", maxSubjectChars+1) + `"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			(&Handlers{}).CreateMessage(w, httptest.NewRequest(http.MethodPost, "/api/v1/messages", strings.NewReader(tt.body)))
+
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d; want %d: %s", w.Code, http.StatusUnprocessableEntity, w.Body)
+			}
+			var resp responseEnvelope
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Details) != 1 || resp.Details[0].Field != "subject" {
+				t.Errorf("details = %+v; want one for subject", resp.Details)
+			}
+		})
+	}
+}
+
+type fakeTenants struct {
+	domain.TenantsRepo
+}
+
+func (fakeTenants) GetTenant(_ context.Context, id string) (domain.Tenant, error) {
+	return domain.Tenant{ID: id}, nil
+}
+
+type fakeSuppressions struct {
+	domain.SuppressionsRepo
+}
+
+func (fakeSuppressions) IsSuppressed(context.Context, string, string, string) (bool, error) {
+	return false, nil
+}
+
+// createdMessages stores every message it is asked to create.
+type createdMessages struct {
+	domain.MessagesRepo
+	created []domain.Message
+}
+
+func (m *createdMessages) Create(_ context.Context, msg domain.Message) (domain.Message, error) {
+	msg.ID = "m-1"
+	m.created = append(m.created, msg)
+	return msg, nil
+}
+
+type fakeDedup struct {
+	claimErr    error
+	completeErr error
+	released    []string
+}
+
+func (d *fakeDedup) ClaimDedup(context.Context, string, time.Duration) (string, bool, error) {
+	return "", d.claimErr == nil, d.claimErr
+}
+
+func (d *fakeDedup) CompleteDedup(context.Context, string, string) error {
+	return d.completeErr
+}
+
+func (d *fakeDedup) ReleaseDedup(_ context.Context, key string) error {
+	d.released = append(d.released, key)
+	return nil
+}
+
+func TestCreateMessageDedupFailures(t *testing.T) {
+	redisDown := errors.New("dial tcp redis:6379: connection refused")
+	tests := []struct {
+		name         string
+		dedup        *fakeDedup
+		wantReleased int
+	}{
+		{name: "claim fails open", dedup: &fakeDedup{claimErr: redisDown}},
+		{name: "failed completion releases the key", dedup: &fakeDedup{completeErr: redisDown}, wantReleased: 1},
+		{name: "completed", dedup: &fakeDedup{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &createdMessages{}
+			h := &Handlers{
+				Repo:         repo,
+				Tenants:      fakeTenants{},
+				Suppressions: fakeSuppressions{},
+				Dedup:        tt.dedup,
+				cfg:          HandlersConfig{Channels: []string{domain.ChannelSMS}, DedupWindow: time.Minute, DedupMode: DedupReject},
+			}
+			body := `{"recipient":"+905551234567","content":"hi"}`
+			w := httptest.NewRecorder()
+			h.CreateMessage(w, httptest.NewRequest(http.MethodPost, "/api/v1/messages", strings.NewReader(body)))
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status = %d; want %d: %s", w.Code, http.StatusCreated, w.Body)
+			}
+			if len(repo.created) != 1 {
+				t.Errorf("created %d messages; want 1", len(repo.created))
+			}
+			if len(tt.dedup.released) != tt.wantReleased {
+				t.Errorf("released %d dedup keys; want %d", len(tt.dedup.released), tt.wantReleased)
+			}
+		})
+	}
+}
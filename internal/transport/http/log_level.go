@@ -0,0 +1,43 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
+)
+
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+func (h *Handlers) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	JSONSuccess(w, http.StatusOK, map[string]string{"level": log.Level.Level().String()})
+}
+
+// SetLogLevel changes the level of this replica only; it resets to LOG_LEVEL
+// on restart.
+func (h *Handlers) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, r, errMalformedBody)
+		return
+	}
+
+	previous := log.Level.Level()
+	if err := log.SetLevel(req.Level); err != nil {
+		WriteError(w, r, invalidField(CodeValidationFailed, "level", err.Error()))
+		return
+	}
+
+	keyID := ""
+	if k, ok := APIKeyFromContext(r.Context()); ok {
+		keyID = k.ID
+	}
+	log.Logger.WarnContext(r.Context(), "log level changed",
+		"from", previous.String(),
+		"to", log.Level.Level().String(),
+		"key_id", keyID,
+	)
+	JSONSuccess(w, http.StatusOK, map[string]string{"level": log.Level.Level().String()})
+}
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMethodLabel(t *testing.T) {
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{method: http.MethodGet, want: "GET"},
+		{method: http.MethodDelete, want: "DELETE"},
+		{method: "get", want: "other"},
+		{method: "PROPFIND", want: "other"},
+		{method: strings.Repeat("X", 64), want: "other"},
+	}
+	for _, tt := range tests {
+		if got := methodLabel(tt.method); got != tt.want {
+			t.Errorf("methodLabel(%q) = %q; want %q", tt.method, got, tt.want)
+		}
+	}
+}
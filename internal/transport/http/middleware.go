@@ -1,20 +1,58 @@
 package http
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/temo927/go-msg-dispatcher/internal/infra/log"
 )
 
+// RequestIDHeader carries the id correlating a request with its logs, its
+// error responses and the messages it created.
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLen = 128
+
+// RequestID takes the caller's X-Request-ID, or generates one if it is
+// missing or unsafe to log, stores it in the request context and echoes it
+// in the response. It runs outermost so even recovered panics carry it.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(log.WithRequestID(r.Context(), id)))
+	})
+}
+
+// RequestLogger logs the outcome of every request; the request id comes
+// from the context set by RequestID.
 func RequestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Logger.Info("request handled",
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if rec.status >= 500 {
+			level = slog.LevelError
+		}
+		log.Logger.Log(r.Context(), level, "request handled",
 			"method", r.Method,
 			"path", r.URL.Path,
+			"status", rec.status,
+			"size", rec.size,
 			"duration", time.Since(start).String(),
+			"remote_addr", r.RemoteAddr,
+			"user_agent", r.UserAgent(),
 		)
 	})
 }
@@ -23,10 +61,34 @@ func RecoverMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if rec := recover(); rec != nil {
-				log.Logger.Error("panic recovered", "panic", rec)
-				http.Error(w, "internal server error", http.StatusInternalServerError)
+				log.Logger.ErrorContext(r.Context(), "panic recovered", "panic", rec)
+				JSONError(w, http.StatusInternalServerError, "internal server error")
 			}
 		}()
 		next.ServeHTTP(w, r)
 	})
 }
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID accepts the ids load balancers and clients commonly send
+// (UUIDs, hex, base64url) but nothing that could forge log fields.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '/', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -11,6 +11,10 @@ type responseEnvelope struct {
 	Status string      `json:"status"`
 	Data   interface{} `json:"data,omitempty"`
 	Error  string      `json:"error,omitempty"`
+	// Code, Details and RequestID are only set on errors; see WriteError.
+	Code      ErrorCode    `json:"code,omitempty"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
 }
 
 func JSONSuccess(w http.ResponseWriter, code int, data interface{}) {
@@ -21,12 +25,20 @@ func JSONSuccess(w http.ResponseWriter, code int, data interface{}) {
 	writeJSON(w, code, resp)
 }
 
+// JSONError answers with message and the generic code of status. Prefer
+// WriteError, which picks specific codes and hides internal errors.
 func JSONError(w http.ResponseWriter, code int, message string) {
-	resp := responseEnvelope{
-		Status: "error",
-		Error:  message,
-	}
-	writeJSON(w, code, resp)
+	writeErrorEnvelope(w, code, codeForStatus(code), message, nil)
+}
+
+func writeErrorEnvelope(w http.ResponseWriter, status int, code ErrorCode, message string, details []FieldError) {
+	writeJSON(w, status, responseEnvelope{
+		Status:    "error",
+		Error:     message,
+		Code:      code,
+		Details:   details,
+		RequestID: w.Header().Get(RequestIDHeader),
+	})
 }
 
 func writeJSON(w http.ResponseWriter, code int, v interface{}) {
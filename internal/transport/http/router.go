@@ -2,17 +2,116 @@ package http
 
 import (
 	"net/http"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/metrics"
 )
 
-func NewRouter(h *Handlers) http.Handler {
+// route is one API endpoint. Routes with an empty scope don't take an API
+// key: probes, scrapes and the delivery callback, which is authenticated by
+// its HMAC signature instead.
+type route struct {
+	pattern string
+	scope   string
+	handler http.HandlerFunc
+}
+
+func routes(h *Handlers, health *Health) []route {
+	return []route{
+		{"POST /api/v1/scheduler/start", domain.ScopeSchedulerAdmin, h.StartScheduler},
+		{"POST /api/v1/scheduler/stop", domain.ScopeSchedulerAdmin, h.StopScheduler},
+		{"GET /api/v1/messages/sent", domain.ScopeMessagesRead, h.ListSent},
+		{"GET /api/v1/messages/{id}", domain.ScopeMessagesRead, h.GetMessage},
+		{"GET /api/v1/messages/{id}/callbacks", domain.ScopeMessagesRead, h.ListStatusCallbacks},
+		{"POST /api/v1/messages", domain.ScopeMessagesWrite, h.CreateMessage},
+		{"GET /api/v1/requests/{request_id}/messages", domain.ScopeMessagesRead, h.ListByRequestID},
+		{"GET /api/v1/events", domain.ScopeMessagesRead, h.StreamEvents},
+		{"POST /api/v1/callbacks/delivery", "", h.DeliveryCallback},
+
+		{"GET /api/v1/templates", domain.ScopeMessagesRead, h.ListTemplates},
+		{"POST /api/v1/templates", domain.ScopeMessagesWrite, h.CreateTemplate},
+		{"GET /api/v1/templates/{id}", domain.ScopeMessagesRead, h.GetTemplate},
+		{"PUT /api/v1/templates/{id}", domain.ScopeMessagesWrite, h.UpdateTemplate},
+		{"DELETE /api/v1/templates/{id}", domain.ScopeMessagesWrite, h.DeleteTemplate},
+
+		{"GET /api/v1/suppressions", domain.ScopeMessagesRead, h.ListSuppressions},
+		{"POST /api/v1/suppressions", domain.ScopeMessagesWrite, h.AddSuppression},
+		{"DELETE /api/v1/suppressions/{channel}/{recipient}", domain.ScopeMessagesWrite, h.RemoveSuppression},
+
+		{"GET /api/v1/keys", domain.ScopeKeysAdmin, h.ListAPIKeys},
+		{"POST /api/v1/keys", domain.ScopeKeysAdmin, h.CreateAPIKey},
+		{"DELETE /api/v1/keys/{id}", domain.ScopeKeysAdmin, h.RevokeAPIKey},
+
+		{"GET /api/v1/tenants", domain.ScopeTenantsAdmin, h.ListTenants},
+		{"POST /api/v1/tenants", domain.ScopeTenantsAdmin, h.CreateTenant},
+		{"GET /api/v1/tenants/{id}", domain.ScopeTenantsAdmin, h.GetTenant},
+		{"PUT /api/v1/tenants/{id}", domain.ScopeTenantsAdmin, h.UpdateTenant},
+
+		{"GET /api/v1/log-level", domain.ScopeOpsAdmin, h.GetLogLevel},
+		{"PUT /api/v1/log-level", domain.ScopeOpsAdmin, h.SetLogLevel},
+
+		{"GET /metrics", "", metrics.Handler().ServeHTTP},
+		{"GET /healthz", "", health.Live},
+		{"GET /readyz", "", health.Ready},
+	}
+}
+
+// NewRouter wires every route behind authentication and, if validator is
+// not nil, validation against the OpenAPI spec, which runs once the caller
+// is known so anonymous requests learn nothing about the expected bodies.
+func NewRouter(h *Handlers, auth *Authenticator, health *Health, validator *RequestValidator) http.Handler {
 	mux := http.NewServeMux()
+	for _, rt := range routes(h, health) {
+		fn := rt.handler
+		if validator != nil {
+			fn = validator.Wrap(rt.pattern, fn)
+		}
+		if rt.scope != "" {
+			fn = auth.Require(rt.scope, fn)
+		}
+		mux.Handle(rt.pattern, withRoute(rt.pattern, fn))
+	}
+	RegisterSwagger(mux)
+
+	return RequestID(RecoverMiddleware(MetricsMiddleware(TracingMiddleware(RequestLogger(jsonFallback(mux))))))
+}
 
-	mux.HandleFunc("/api/v1/scheduler/start", h.StartScheduler)
-	mux.HandleFunc("/api/v1/scheduler/stop", h.StopScheduler)
-	mux.HandleFunc("/api/v1/messages/sent", h.ListSent)
-	mux.HandleFunc("/api/v1/messages", h.CreateMessage)
+// jsonFallback answers requests no route matches with the JSON error
+// envelope instead of ServeMux's plain-text 404 and 405 bodies. The Allow
+// header ServeMux sets on 405s is kept.
+func jsonFallback(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		mux.ServeHTTP(&fallbackWriter{ResponseWriter: w}, r)
+	})
+}
+
+// fallbackWriter replaces ServeMux's own 404 and 405 responses. Anything
+// else it writes, such as trailing-slash redirects, passes through.
+type fallbackWriter struct {
+	http.ResponseWriter
+	replaced bool
+}
 
-	RegisterSwagger(mux, "internal/transport/http/swagger")
+func (w *fallbackWriter) WriteHeader(code int) {
+	switch code {
+	case http.StatusNotFound:
+		w.replaced = true
+		writeErrorEnvelope(w.ResponseWriter, code, CodeRouteNotFound, "route not found", nil)
+	case http.StatusMethodNotAllowed:
+		w.replaced = true
+		JSONError(w.ResponseWriter, code, "method not allowed; allowed: "+w.Header().Get("Allow"))
+	default:
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
 
-	return RecoverMiddleware(RequestLogger(mux))
+func (w *fallbackWriter) Write(b []byte) (int, error) {
+	if w.replaced {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
 }
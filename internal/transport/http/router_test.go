@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEveryRouteIsInOpenAPISpec(t *testing.T) {
+	v, err := NewRequestValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rt := range routes(&Handlers{}, &Health{}) {
+		if _, ok := v.operation(rt.pattern); !ok {
+			t.Errorf("route %q is not documented in openapi.yaml", rt.pattern)
+		}
+	}
+}
+
+func TestValidatorLimitsBody(t *testing.T) {
+	v, err := NewRequestValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reached := false
+	h := v.Wrap("POST /api/v1/callbacks/delivery", func(w http.ResponseWriter, r *http.Request) { reached = true })
+
+	body := `{"provider_message_id":"` + strings.Repeat("x", maxRequestBody) + `","status":"delivered"}`
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/callbacks/delivery", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(deliverySignatureHeader, "t=1,v1=00")
+	h(w, r)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d; want %d: %s", w.Code, http.StatusRequestEntityTooLarge, w.Body)
+	}
+	if reached {
+		t.Error("oversized body reached the handler")
+	}
+}
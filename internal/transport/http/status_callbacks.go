@@ -0,0 +1,82 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/infra/webhook"
+)
+
+// maxCallbackURLLen matches the messages.callback_url column.
+const maxCallbackURLLen = 2048
+
+// validateCallbackURL accepts absolute http(s) URLs without credentials
+// whose host passes webhook.CheckCallbackHost. The callback client checks
+// the resolved address again on every connection.
+func validateCallbackURL(raw string, allowedHosts []string) *APIError {
+	if len(raw) > maxCallbackURLLen {
+		return invalidField(CodeValidationFailed, "callback_url", fmt.Sprintf("callback_url exceeds %d characters", maxCallbackURLLen))
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return invalidField(CodeValidationFailed, "callback_url", "callback_url must be an absolute http or https URL")
+	}
+	if u.User != nil {
+		return invalidField(CodeValidationFailed, "callback_url", "callback_url must not contain credentials")
+	}
+	if err := webhook.CheckCallbackHost(u.Hostname(), allowedHosts); err != nil {
+		return invalidField(CodeValidationFailed, "callback_url", "callback_url host is not allowed")
+	}
+	return nil
+}
+
+// ListStatusCallbacks shows the status callbacks of a message with their
+// delivery log, so creators can see why a callback never arrived.
+func (h *Handlers) ListStatusCallbacks(w http.ResponseWriter, r *http.Request) {
+	tenantID, admin := callerTenant(r)
+	if admin {
+		tenantID = ""
+	}
+
+	m, err := h.Repo.GetMessage(r.Context(), tenantID, r.PathValue("id"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	cbs, err := h.Callbacks.ListCallbacks(r.Context(), m.TenantID, m.ID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	resp := make([]map[string]any, 0, len(cbs))
+	for _, cb := range cbs {
+		attempts := make([]map[string]any, 0, len(cb.Log))
+		for _, a := range cb.Log {
+			attempts = append(attempts, map[string]any{
+				"attempt":      a.Attempt,
+				"status_code":  a.StatusCode,
+				"error":        a.Error,
+				"duration_ms":  a.Duration.Milliseconds(),
+				"attempted_at": a.AttemptedAt,
+			})
+		}
+		item := map[string]any{
+			"id":           cb.ID,
+			"url":          cb.URL,
+			"status":       cb.Status,
+			"attempts":     cb.Attempts,
+			"last_error":   cb.LastError,
+			"created_at":   cb.CreatedAt,
+			"delivered_at": cb.DeliveredAt,
+			"log":          attempts,
+		}
+		if cb.Status == domain.CallbackPending {
+			item["next_attempt_at"] = cb.NextAttemptAt
+		}
+		resp = append(resp, item)
+	}
+	JSONSuccess(w, http.StatusOK, map[string]any{"items": resp, "count": len(resp)})
+}
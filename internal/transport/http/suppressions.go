@@ -0,0 +1,123 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+type suppressionRequest struct {
+	Channel   string `json:"channel"`
+	Recipient string `json:"recipient"`
+	Reason    string `json:"reason"`
+	// TenantID defaults to the caller's tenant; only tenant admins may pick
+	// another one.
+	TenantID string `json:"tenant_id"`
+}
+
+// ListSuppressions lists the caller's tenant. Tenant admins see every
+// tenant, or the one given in ?tenant_id.
+func (h *Handlers) ListSuppressions(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pagination(r, 50)
+
+	tenantID, admin := callerTenant(r)
+	if admin {
+		tenantID = r.URL.Query().Get("tenant_id")
+	}
+
+	items, err := h.Suppressions.ListSuppressions(r.Context(), tenantID, limit, offset)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	resp := make([]map[string]any, 0, len(items))
+	for _, s := range items {
+		resp = append(resp, suppressionResponse(s))
+	}
+	JSONSuccess(w, http.StatusOK, map[string]any{"items": resp, "count": len(resp)})
+}
+
+func (h *Handlers) AddSuppression(w http.ResponseWriter, r *http.Request) {
+	var req suppressionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, r, errMalformedBody)
+		return
+	}
+	if req.Channel == "" {
+		req.Channel = domain.ChannelSMS
+	}
+	if req.Recipient == "" {
+		WriteError(w, r, missingFields("recipient is required", "recipient"))
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = "opt_out"
+	}
+
+	recipient, err := h.normalizeRecipient(req.Channel, req.Recipient)
+	if err != nil {
+		WriteError(w, r, invalidField(CodeInvalidRecipient, "recipient", err.Error()))
+		return
+	}
+
+	callerTenantID, admin := callerTenant(r)
+	if req.TenantID == "" {
+		req.TenantID = callerTenantID
+	}
+	if !admin && req.TenantID != callerTenantID {
+		WriteError(w, r, forbiddenField(CodeForbidden, "tenant_id", "only tenant admins can suppress recipients for other tenants"))
+		return
+	}
+
+	s, err := h.Suppressions.AddSuppression(r.Context(), domain.Suppression{
+		TenantID:  req.TenantID,
+		Channel:   req.Channel,
+		Recipient: recipient,
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrTenantNotFound) {
+			WriteError(w, r, invalidField(CodeTenantNotFound, "tenant_id", fmt.Sprintf("tenant %q does not exist", req.TenantID)))
+			return
+		}
+		WriteError(w, r, err)
+		return
+	}
+	JSONSuccess(w, http.StatusCreated, suppressionResponse(s))
+}
+
+// RemoveSuppression removes a recipient from the caller's list. Tenant
+// admins may name another tenant in ?tenant_id.
+func (h *Handlers) RemoveSuppression(w http.ResponseWriter, r *http.Request) {
+	tenantID, admin := callerTenant(r)
+	if t := r.URL.Query().Get("tenant_id"); admin && t != "" {
+		tenantID = t
+	}
+
+	channel := r.PathValue("channel")
+	recipient, err := h.normalizeRecipient(channel, r.PathValue("recipient"))
+	if err != nil {
+		WriteError(w, r, invalidField(CodeInvalidRecipient, "recipient", err.Error()))
+		return
+	}
+
+	if err := h.Suppressions.RemoveSuppression(r.Context(), tenantID, channel, recipient); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	JSONSuccess(w, http.StatusOK, map[string]string{"message": "suppression removed"})
+}
+
+func suppressionResponse(s domain.Suppression) map[string]any {
+	return map[string]any{
+		"tenant_id":  s.TenantID,
+		"channel":    s.Channel,
+		"recipient":  s.Recipient,
+		"reason":     s.Reason,
+		"created_at": s.CreatedAt,
+	}
+}
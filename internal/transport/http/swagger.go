@@ -1,14 +1,32 @@
 package http
 
 import (
+	"embed"
+	"io/fs"
 	"net/http"
-	"path/filepath"
 )
 
-func RegisterSwagger(mux *http.ServeMux, swaggerDir string) {
-	if swaggerDir == "" {
-		return
+// The docs are compiled in, so they are served whatever directory the
+// binary is started from and always match the handlers it was built with.
+//
+//go:embed swagger/index.html swagger/openapi.yaml
+var swaggerFiles embed.FS
+
+// OpenAPISpec returns the embedded openapi.yaml.
+func OpenAPISpec() []byte {
+	b, err := swaggerFiles.ReadFile("swagger/openapi.yaml")
+	if err != nil {
+		panic("openapi.yaml is not embedded: " + err.Error())
+	}
+	return b
+}
+
+// RegisterSwagger serves the UI at /swagger/ and the spec at
+// /swagger/openapi.yaml.
+func RegisterSwagger(mux *http.ServeMux) {
+	docs, err := fs.Sub(swaggerFiles, "swagger")
+	if err != nil {
+		panic(err)
 	}
-	fs := http.FileServer(http.Dir(filepath.Clean(swaggerDir)))
-	mux.Handle("/swagger/", http.StripPrefix("/swagger/", fs))
+	mux.Handle("GET /swagger/", withRoute("GET /swagger/", http.StripPrefix("/swagger/", http.FileServerFS(docs))))
 }
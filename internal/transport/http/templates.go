@@ -0,0 +1,214 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+	"github.com/temo927/go-msg-dispatcher/internal/msgtemplate"
+)
+
+type templateRequest struct {
+	Name          string            `json:"name"`
+	DefaultLocale string            `json:"default_locale"`
+	Bodies        map[string]string `json:"bodies"`
+	// TenantID defaults to the caller's tenant; only tenant admins may pick
+	// another one.
+	TenantID string `json:"tenant_id"`
+}
+
+// templateTenant returns the tenant whose templates the request may touch:
+// the caller's, or any ("") for tenant admins.
+func templateTenant(r *http.Request) string {
+	tenantID, admin := callerTenant(r)
+	if admin {
+		return ""
+	}
+	return tenantID
+}
+
+func (h *Handlers) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req templateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, r, errMalformedBody)
+		return
+	}
+	var missing []string
+	if req.Name == "" {
+		missing = append(missing, "name")
+	}
+	if req.DefaultLocale == "" {
+		missing = append(missing, "default_locale")
+	}
+	if len(req.Bodies) == 0 {
+		missing = append(missing, "bodies")
+	}
+	if len(missing) > 0 {
+		WriteError(w, r, missingFields("name, default_locale and bodies are required", missing...))
+		return
+	}
+	if apiErr := validateBodies(req.DefaultLocale, req.Bodies); apiErr != nil {
+		WriteError(w, r, apiErr)
+		return
+	}
+
+	callerTenantID, admin := callerTenant(r)
+	if req.TenantID == "" {
+		req.TenantID = callerTenantID
+	}
+	if !admin && req.TenantID != callerTenantID {
+		WriteError(w, r, forbiddenField(CodeForbidden, "tenant_id", "only tenant admins can create templates for other tenants"))
+		return
+	}
+
+	t, err := h.Templates.CreateTemplate(r.Context(), domain.Template{
+		TenantID:      req.TenantID,
+		Name:          req.Name,
+		DefaultLocale: req.DefaultLocale,
+		Bodies:        req.Bodies,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrTenantNotFound) {
+			WriteError(w, r, invalidField(CodeTenantNotFound, "tenant_id", fmt.Sprintf("tenant %q does not exist", req.TenantID)))
+			return
+		}
+		WriteError(w, r, err)
+		return
+	}
+	JSONSuccess(w, http.StatusCreated, templateResponse(t))
+}
+
+// UpdateTemplate stores the request bodies as a new version; older versions
+// stay available to messages that pinned them.
+func (h *Handlers) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req templateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, r, errMalformedBody)
+		return
+	}
+	if len(req.Bodies) == 0 {
+		WriteError(w, r, missingFields("bodies are required", "bodies"))
+		return
+	}
+
+	defaultLocale := req.DefaultLocale
+	if defaultLocale == "" {
+		current, err := h.Templates.GetTemplate(r.Context(), templateTenant(r), r.PathValue("id"), 0)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		defaultLocale = current.DefaultLocale
+	}
+	if apiErr := validateBodies(defaultLocale, req.Bodies); apiErr != nil {
+		WriteError(w, r, apiErr)
+		return
+	}
+
+	t, err := h.Templates.AddTemplateVersion(r.Context(), templateTenant(r), r.PathValue("id"), req.DefaultLocale, req.Bodies)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	JSONSuccess(w, http.StatusOK, templateResponse(t))
+}
+
+func (h *Handlers) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	version := 0
+	if v := r.URL.Query().Get("version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			WriteError(w, r, &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    CodeInvalidRequest,
+				Message: "version must be a positive integer",
+				Details: []FieldError{{Field: "version", Message: "must be a positive integer"}},
+			})
+			return
+		}
+		version = n
+	}
+
+	t, err := h.Templates.GetTemplate(r.Context(), templateTenant(r), r.PathValue("id"), version)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	JSONSuccess(w, http.StatusOK, templateResponse(t))
+}
+
+// ListTemplates lists the caller's tenant. Tenant admins see every tenant,
+// or the one given in ?tenant_id.
+func (h *Handlers) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pagination(r, 50)
+
+	tenantID, admin := callerTenant(r)
+	if admin {
+		tenantID = r.URL.Query().Get("tenant_id")
+	}
+
+	ts, err := h.Templates.ListTemplates(r.Context(), tenantID, limit, offset)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	resp := make([]map[string]any, 0, len(ts))
+	for _, t := range ts {
+		resp = append(resp, templateResponse(t))
+	}
+	JSONSuccess(w, http.StatusOK, map[string]any{"items": resp, "count": len(resp)})
+}
+
+func (h *Handlers) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := h.Templates.DeleteTemplate(r.Context(), templateTenant(r), r.PathValue("id")); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	JSONSuccess(w, http.StatusOK, map[string]string{"message": "template deleted"})
+}
+
+func validateBodies(defaultLocale string, bodies map[string]string) *APIError {
+	if _, ok := bodies[defaultLocale]; !ok {
+		return invalidField(CodeValidationFailed, "bodies", "bodies must include the default_locale "+defaultLocale)
+	}
+	for locale, body := range bodies {
+		if body == "" {
+			return invalidField(CodeValidationFailed, "bodies."+locale, "body for locale "+locale+" is empty")
+		}
+		if err := msgtemplate.Validate(body); err != nil {
+			return invalidField(CodeValidationFailed, "bodies."+locale, "locale "+locale+": "+err.Error())
+		}
+	}
+	return nil
+}
+
+func templateResponse(t domain.Template) map[string]any {
+	vars := map[string]bool{}
+	for _, body := range t.Bodies {
+		for _, v := range msgtemplate.Variables(body) {
+			vars[v] = true
+		}
+	}
+	names := make([]string, 0, len(vars))
+	for v := range vars {
+		names = append(names, v)
+	}
+	slices.Sort(names)
+
+	return map[string]any{
+		"id":             t.ID,
+		"tenant_id":      t.TenantID,
+		"name":           t.Name,
+		"version":        t.Version,
+		"default_locale": t.DefaultLocale,
+		"bodies":         t.Bodies,
+		"variables":      names,
+		"created_at":     t.CreatedAt,
+		"updated_at":     t.UpdatedAt,
+	}
+}
@@ -0,0 +1,191 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/temo927/go-msg-dispatcher/internal/domain"
+)
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+const maskedSecret = "***"
+
+// minCallbackSecretLen keeps callback signing secrets out of guessing range.
+const minCallbackSecretLen = 32
+
+type tenantRequest struct {
+	ID         string                           `json:"id"`
+	Name       string                           `json:"name"`
+	DailyQuota *int                             `json:"daily_quota"`
+	Providers  map[string]domain.ProviderConfig `json:"providers"`
+	// CallbackSigningSecrets sign the tenant's status callbacks.
+	CallbackSigningSecrets []string `json:"callback_signing_secrets"`
+}
+
+func (h *Handlers) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	var req tenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, r, errMalformedBody)
+		return
+	}
+	var missing []string
+	if req.ID == "" {
+		missing = append(missing, "id")
+	}
+	if req.Name == "" {
+		missing = append(missing, "name")
+	}
+	if len(missing) > 0 {
+		WriteError(w, r, missingFields("id and name are required", missing...))
+		return
+	}
+	if !tenantIDPattern.MatchString(req.ID) {
+		WriteError(w, r, invalidField(CodeValidationFailed, "id", "id must be 1-64 lowercase letters, digits, '-' or '_'"))
+		return
+	}
+	if apiErr := validateTenant(req); apiErr != nil {
+		WriteError(w, r, apiErr)
+		return
+	}
+
+	t, err := h.Tenants.CreateTenant(r.Context(), domain.Tenant{
+		ID:                     req.ID,
+		Name:                   req.Name,
+		DailyQuota:             req.DailyQuota,
+		Providers:              req.Providers,
+		CallbackSigningSecrets: req.CallbackSigningSecrets,
+	})
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	JSONSuccess(w, http.StatusCreated, tenantResponse(t))
+}
+
+// UpdateTenant replaces the tenant's name, quota, providers and callback
+// signing secrets; omitted fields are cleared, except the name. A masked
+// auth_value or callback signing secret as returned by GET keeps the stored
+// secret, so responses can be edited and sent back.
+func (h *Handlers) UpdateTenant(w http.ResponseWriter, r *http.Request) {
+	var req tenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, r, errMalformedBody)
+		return
+	}
+	current, err := h.Tenants.GetTenant(r.Context(), r.PathValue("id"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if req.Name == "" {
+		req.Name = current.Name
+	}
+	for ch, p := range req.Providers {
+		if p.AuthValue == maskedSecret {
+			p.AuthValue = current.Providers[ch].AuthValue
+			req.Providers[ch] = p
+		}
+	}
+	for i, secret := range req.CallbackSigningSecrets {
+		if secret == maskedSecret && i < len(current.CallbackSigningSecrets) {
+			req.CallbackSigningSecrets[i] = current.CallbackSigningSecrets[i]
+		}
+	}
+	if apiErr := validateTenant(req); apiErr != nil {
+		WriteError(w, r, apiErr)
+		return
+	}
+
+	t, err := h.Tenants.UpdateTenant(r.Context(), domain.Tenant{
+		ID:                     current.ID,
+		Name:                   req.Name,
+		DailyQuota:             req.DailyQuota,
+		Providers:              req.Providers,
+		CallbackSigningSecrets: req.CallbackSigningSecrets,
+	})
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	JSONSuccess(w, http.StatusOK, tenantResponse(t))
+}
+
+func (h *Handlers) GetTenant(w http.ResponseWriter, r *http.Request) {
+	t, err := h.Tenants.GetTenant(r.Context(), r.PathValue("id"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	JSONSuccess(w, http.StatusOK, tenantResponse(t))
+}
+
+func (h *Handlers) ListTenants(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pagination(r, 50)
+
+	ts, err := h.Tenants.ListTenants(r.Context(), limit, offset)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	resp := make([]map[string]any, 0, len(ts))
+	for _, t := range ts {
+		resp = append(resp, tenantResponse(t))
+	}
+	JSONSuccess(w, http.StatusOK, map[string]any{"items": resp, "count": len(resp)})
+}
+
+// validateTenant checks the quota and provider overrides. Tenants can only
+// bring their own webhook providers, so email stays on the global SMTP relay.
+func validateTenant(req tenantRequest) *APIError {
+	if req.DailyQuota != nil && *req.DailyQuota < 0 {
+		return invalidField(CodeValidationFailed, "daily_quota", "daily_quota must not be negative")
+	}
+	for ch, p := range req.Providers {
+		if ch != domain.ChannelSMS && ch != domain.ChannelPush {
+			return invalidField(CodeUnsupportedChannel, "providers."+ch,
+				fmt.Sprintf("providers: channel %q can't have a tenant provider (sms, push)", ch))
+		}
+		u, err := url.Parse(p.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return invalidField(CodeValidationFailed, "providers."+ch+".url",
+				fmt.Sprintf("providers.%s.url must be an absolute http(s) URL", ch))
+		}
+	}
+	for i, secret := range req.CallbackSigningSecrets {
+		if len(secret) < minCallbackSecretLen {
+			return invalidField(CodeValidationFailed, fmt.Sprintf("callback_signing_secrets[%d]", i),
+				fmt.Sprintf("callback signing secrets must be at least %d characters", minCallbackSecretLen))
+		}
+	}
+	return nil
+}
+
+// tenantResponse never echoes provider credentials or callback signing
+// secrets back.
+func tenantResponse(t domain.Tenant) map[string]any {
+	providers := make(map[string]domain.ProviderConfig, len(t.Providers))
+	for ch, p := range t.Providers {
+		if p.AuthValue != "" {
+			p.AuthValue = maskedSecret
+		}
+		providers[ch] = p
+	}
+	secrets := make([]string, len(t.CallbackSigningSecrets))
+	for i := range secrets {
+		secrets[i] = maskedSecret
+	}
+	return map[string]any{
+		"id":                       t.ID,
+		"name":                     t.Name,
+		"daily_quota":              t.DailyQuota,
+		"providers":                providers,
+		"callback_signing_secrets": secrets,
+		"created_at":               t.CreatedAt,
+		"updated_at":               t.UpdatedAt,
+	}
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/temo927/go-msg-dispatcher/internal/infra/tracing"
+)
+
+// TracingMiddleware starts a server span per request, continuing the caller's
+// trace if it sent a traceparent. It must run inside MetricsMiddleware, whose
+// route and status it reuses for the span name and attributes. Only the route
+// pattern is recorded: paths carry recipients and other ids.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		method := methodLabel(r.Method)
+		ctx, span := tracing.Tracer.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+
+		if route, ok := ctx.Value(routeCtxKey{}).(*string); ok && *route != "" {
+			span.SetName(method + " " + *route)
+			span.SetAttributes(semconv.HTTPRoute(*route))
+		}
+		if rec, ok := w.(*statusRecorder); ok {
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			if status >= 500 {
+				span.SetStatus(codes.Error, fmt.Sprintf("status %d", status))
+			}
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+// maxRequestBody caps the bodies Wrap reads, which includes requests to the
+// unauthenticated delivery callback.
+const maxRequestBody = 1 << 20
+
+// RequestValidator rejects requests whose query, path parameters or body
+// don't match openapi.yaml before they reach a handler.
+type RequestValidator struct {
+	spec *openapi3.T
+}
+
+// NewRequestValidator loads the embedded spec.
+func NewRequestValidator() (*RequestValidator, error) {
+	loader := openapi3.NewLoader()
+	spec, err := loader.LoadFromData(OpenAPISpec())
+	if err != nil {
+		return nil, fmt.Errorf("load openapi spec: %w", err)
+	}
+	if err := spec.Validate(loader.Context, openapi3.DisableExamplesValidation()); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+	return &RequestValidator{spec: spec}, nil
+}
+
+// operation finds the spec operation documenting a ServeMux pattern such as
+// "GET /api/v1/templates/{id}"; both use the same path syntax.
+func (v *RequestValidator) operation(pattern string) (*routers.Route, bool) {
+	method, path, _ := strings.Cut(pattern, " ")
+	item := v.spec.Paths.Find(path)
+	if item == nil {
+		return nil, false
+	}
+	op := item.GetOperation(method)
+	if op == nil {
+		return nil, false
+	}
+	return &routers.Route{Spec: v.spec, Path: path, PathItem: item, Method: method, Operation: op}, true
+}
+
+// Wrap validates requests to pattern. Routes missing from the spec pass
+// through unchecked; the router test keeps that from happening.
+func (v *RequestValidator) Wrap(pattern string, next http.HandlerFunc) http.HandlerFunc {
+	route, ok := v.operation(pattern)
+	if !ok {
+		return next
+	}
+
+	var pathParams []string
+	for _, p := range route.Operation.Parameters {
+		if p.Value != nil && p.Value.In == openapi3.ParameterInPath {
+			pathParams = append(pathParams, p.Value.Name)
+		}
+	}
+	for _, p := range route.PathItem.Parameters {
+		if p.Value != nil && p.Value.In == openapi3.ParameterInPath {
+			pathParams = append(pathParams, p.Value.Name)
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := make(map[string]string, len(pathParams))
+		for _, name := range pathParams {
+			params[name] = r.PathValue(name)
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+
+		err := openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: params,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				MultiError: true,
+				// API keys are checked by Authenticator, before this runs.
+				AuthenticationFunc: func(context.Context, *openapi3filter.AuthenticationInput) error { return nil },
+			},
+		})
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			WriteError(w, r, &APIError{
+				Status:  http.StatusRequestEntityTooLarge,
+				Code:    CodeBodyTooLarge,
+				Message: fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit),
+			})
+			return
+		}
+		if err != nil {
+			WriteError(w, r, &APIError{
+				Status:  http.StatusBadRequest,
+				Code:    CodeInvalidRequest,
+				Message: "request does not match the API specification",
+				Details: validationDetails(err),
+			})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// validationDetails flattens kin-openapi's nested errors into one entry per
+// offending field. Body fields are named by their JSON path, e.g.
+// "providers.sms.url".
+func validationDetails(err error) []FieldError {
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		var details []FieldError
+		for _, e := range multi {
+			details = append(details, validationDetails(e)...)
+		}
+		return details
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		field := strings.Join(schemaErr.JSONPointer(), ".")
+		var reqErr *openapi3filter.RequestError
+		if errors.As(err, &reqErr) && reqErr.Parameter != nil {
+			field = reqErr.Parameter.Name
+		}
+		if field == "" {
+			field = "body"
+		}
+		return []FieldError{{Field: field, Message: schemaErr.Reason}}
+	}
+
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		if reqErr.Err != nil {
+			var nested openapi3.MultiError
+			if errors.As(reqErr.Err, &nested) {
+				return validationDetails(nested)
+			}
+		}
+		field := "body"
+		if reqErr.Parameter != nil {
+			field = reqErr.Parameter.Name
+		}
+		msg := reqErr.Reason
+		if msg == "" && reqErr.Err != nil {
+			msg = reqErr.Err.Error()
+		}
+		return []FieldError{{Field: field, Message: msg}}
+	}
+
+	return []FieldError{{Field: "request", Message: err.Error()}}
+}
@@ -0,0 +1,138 @@
+// Package webhooksig signs and verifies the webhooks sent by the dispatcher.
+//
+// Every request carries a header of the form
+//
+//	X-Webhook-Signature: t=1728150664,v1=5257a869e7...,v1=6ffbb59b2...
+//
+// where t is the unix timestamp the request was signed at and each v1 is the
+// hex HMAC-SHA256 of "<t>.<body>" under one of the active secrets. Signing with
+// every active secret lets secrets be rotated without downtime: receivers accept
+// the request as long as any of their secrets matches any v1 entry.
+package webhooksig
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultHeader is the header the signature is sent in unless configured otherwise.
+	DefaultHeader = "X-Webhook-Signature"
+	// DefaultTolerance is how far a timestamp may drift before a request is treated as a replay.
+	DefaultTolerance = 5 * time.Minute
+	// MaxBodyBytes is the largest body VerifyRequest reads.
+	MaxBodyBytes = 1 << 20
+)
+
+var (
+	ErrMissingSignature = errors.New("webhooksig: missing signature")
+	ErrInvalidHeader    = errors.New("webhooksig: malformed signature header")
+	ErrExpired          = errors.New("webhooksig: timestamp outside tolerance")
+	ErrMismatch         = errors.New("webhooksig: no matching signature")
+	ErrBodyTooLarge     = errors.New("webhooksig: body exceeds MaxBodyBytes")
+)
+
+// Sign returns the signature header value for body at time ts, with one v1
+// entry per secret.
+func Sign(body []byte, ts time.Time, secrets ...string) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	parts := make([]string, 0, len(secrets)+1)
+	parts = append(parts, "t="+t)
+	for _, s := range secrets {
+		parts = append(parts, "v1="+hex.EncodeToString(compute(t, body, s)))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Verify checks header against body using any of secrets. A tolerance of zero
+// uses DefaultTolerance; a negative tolerance disables the timestamp check.
+func Verify(body []byte, header string, secrets []string, tolerance time.Duration) error {
+	return verifyAt(body, header, secrets, tolerance, time.Now())
+}
+
+// VerifyRequest verifies r against secrets using the signature in header
+// (DefaultHeader if empty) and restores r.Body so handlers can still read it.
+// Bodies over MaxBodyBytes are rejected with ErrBodyTooLarge unread.
+func VerifyRequest(r *http.Request, header string, secrets []string, tolerance time.Duration) error {
+	if header == "" {
+		header = DefaultHeader
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > MaxBodyBytes {
+		return ErrBodyTooLarge
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return Verify(body, r.Header.Get(header), secrets, tolerance)
+}
+
+func verifyAt(body []byte, header string, secrets []string, tolerance time.Duration, now time.Time) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	var t string
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrInvalidHeader
+		}
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			sig, err := hex.DecodeString(v)
+			if err != nil {
+				return ErrInvalidHeader
+			}
+			sigs = append(sigs, sig)
+		}
+	}
+	if t == "" || len(sigs) == 0 {
+		return ErrInvalidHeader
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return ErrInvalidHeader
+	}
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	if tolerance > 0 {
+		drift := now.Sub(time.Unix(unix, 0))
+		if drift > tolerance || drift < -tolerance {
+			return ErrExpired
+		}
+	}
+
+	for _, s := range secrets {
+		want := compute(t, body, s)
+		for _, sig := range sigs {
+			if hmac.Equal(sig, want) {
+				return nil
+			}
+		}
+	}
+	return ErrMismatch
+}
+
+func compute(t string, body []byte, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
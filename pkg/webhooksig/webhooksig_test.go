@@ -0,0 +1,186 @@
+package webhooksig
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"42","status":"sent"}`)
+	now := time.Unix(1728150664, 0)
+
+	tests := []struct {
+		name      string
+		body      []byte
+		header    string
+		secrets   []string
+		tolerance time.Duration
+		want      error
+	}{
+		{
+			name:    "valid",
+			body:    body,
+			header:  Sign(body, now, "s1"),
+			secrets: []string{"s1"},
+		},
+		{
+			name:    "signed with old and new secret, receiver has new",
+			body:    body,
+			header:  Sign(body, now, "old", "new"),
+			secrets: []string{"new"},
+		},
+		{
+			name:    "signed with new secret, receiver still has both",
+			body:    body,
+			header:  Sign(body, now, "new"),
+			secrets: []string{"old", "new"},
+		},
+		{
+			name:    "secret already rotated out",
+			body:    body,
+			header:  Sign(body, now, "new"),
+			secrets: []string{"old"},
+			want:    ErrMismatch,
+		},
+		{
+			name:    "tampered body",
+			body:    []byte(`{"id":"42","status":"failed"}`),
+			header:  Sign(body, now, "s1"),
+			secrets: []string{"s1"},
+			want:    ErrMismatch,
+		},
+		{
+			name:    "within default tolerance",
+			body:    body,
+			header:  Sign(body, now.Add(-4*time.Minute), "s1"),
+			secrets: []string{"s1"},
+		},
+		{
+			name:    "older than default tolerance",
+			body:    body,
+			header:  Sign(body, now.Add(-6*time.Minute), "s1"),
+			secrets: []string{"s1"},
+			want:    ErrExpired,
+		},
+		{
+			name:    "from the future",
+			body:    body,
+			header:  Sign(body, now.Add(6*time.Minute), "s1"),
+			secrets: []string{"s1"},
+			want:    ErrExpired,
+		},
+		{
+			name:      "custom tolerance",
+			body:      body,
+			header:    Sign(body, now.Add(-time.Minute), "s1"),
+			secrets:   []string{"s1"},
+			tolerance: 30 * time.Second,
+			want:      ErrExpired,
+		},
+		{
+			name:      "timestamp check disabled",
+			body:      body,
+			header:    Sign(body, now.Add(-time.Hour), "s1"),
+			secrets:   []string{"s1"},
+			tolerance: -1,
+		},
+		{
+			name:    "missing header",
+			body:    body,
+			secrets: []string{"s1"},
+			want:    ErrMissingSignature,
+		},
+		{
+			name:    "no timestamp",
+			body:    body,
+			header:  "v1=" + strings.Repeat("ab", 32),
+			secrets: []string{"s1"},
+			want:    ErrInvalidHeader,
+		},
+		{
+			name:    "no signature",
+			body:    body,
+			header:  "t=1728150664",
+			secrets: []string{"s1"},
+			want:    ErrInvalidHeader,
+		},
+		{
+			name:    "signature not hex",
+			body:    body,
+			header:  "t=1728150664,v1=zz",
+			secrets: []string{"s1"},
+			want:    ErrInvalidHeader,
+		},
+		{
+			name:    "timestamp not a number",
+			body:    body,
+			header:  "t=yesterday,v1=" + strings.Repeat("ab", 32),
+			secrets: []string{"s1"},
+			want:    ErrInvalidHeader,
+		},
+		{
+			name:    "part without equals sign",
+			body:    body,
+			header:  "t=1728150664,garbage",
+			secrets: []string{"s1"},
+			want:    ErrInvalidHeader,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyAt(tt.body, tt.header, tt.secrets, tt.tolerance, now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("verifyAt() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignHasOneEntryPerSecret(t *testing.T) {
+	h := Sign([]byte("x"), time.Unix(1, 0), "a", "b", "c")
+	if got := strings.Count(h, "v1="); got != 3 {
+		t.Errorf("Sign() = %q, want 3 v1 entries", h)
+	}
+	if !strings.HasPrefix(h, "t=1,") {
+		t.Errorf("Sign() = %q, want it to start with t=1,", h)
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	body := `{"id":"42"}`
+
+	tests := []struct {
+		name   string
+		body   string
+		header string
+		sentIn string
+		want   error
+	}{
+		{name: "default header", body: body, sentIn: DefaultHeader},
+		{name: "custom header", body: body, header: "X-Outbox-Signature", sentIn: "X-Outbox-Signature"},
+		{name: "signature in another header", body: body, header: "X-Outbox-Signature", sentIn: DefaultHeader, want: ErrMissingSignature},
+		{name: "body too large", body: strings.Repeat("a", MaxBodyBytes+1), sentIn: DefaultHeader, want: ErrBodyTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/hook", strings.NewReader(tt.body))
+			r.Header.Set(tt.sentIn, Sign([]byte(tt.body), time.Now(), "s1"))
+
+			err := VerifyRequest(r, tt.header, []string{"s1"}, 0)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("VerifyRequest() = %v, want %v", err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			rest, _ := io.ReadAll(r.Body)
+			if string(rest) != tt.body {
+				t.Errorf("body after VerifyRequest = %q, want %q", rest, tt.body)
+			}
+		})
+	}
+}